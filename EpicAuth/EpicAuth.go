@@ -1,25 +1,15 @@
 package EpicAuth
 
 import (
-	"bytes"
-	"crypto/ed25519"
 	"fmt"
 	"io"
 	"os"
 	"runtime"
-	"strconv"
-	"strings"
-	"time"
 
 	"crypto/md5"
-	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
-	"io/ioutil"
-	"net/http"
-	"net/url"
 	"os/exec"
-	"path/filepath"
 )
 
 var (
@@ -49,781 +39,234 @@ var (
 	PublicKey     string = "95b38710f40927b16528a073b87d942e03bd4578d49963a19ebae177945f89ac"
 )
 
-func Api(name, ownerid, version, path string) {
-	if name == "" || ownerid == "" || version == "" || len(ownerid) != 10 {
-		fmt.Println("Application not set up properly.")
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	Name = name
-	OwnerID = ownerid
-	Version = version
-	TokenPath = path
-	if path == "null" {
-		TokenPath = ""
-	}
-
-	Init()
-}
-
-func Init() {
-	if SessionID != "lol" {
-		fmt.Println("You have already initialized this application.")
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
+// RequireNonce makes the package-level functions reject responses that
+// don't echo the request's nonce, like WithRequireNonce. Set it before Api.
+var RequireNonce bool
 
-	postData := map[string]string{
-		"type":    "init",
-		"ver":     Version,
-		"hash":    checkSum(filepath.Base(os.Args[0])),
-		"name":    Name,
-		"ownerid": OwnerID,
-	}
+// std is the client used by the package-level functions. It is created by
+// Api, or lazily from the package variables if Init is called directly.
+var std *Client
 
-	if TokenPath != "" {
-		token, err := ioutil.ReadFile(TokenPath)
-		if err != nil {
-			fmt.Println("Error reading token file: " + err.Error())
-		}
-		postData["token"] = string(token)
-		postData["thash"] = tokenHash(TokenPath)
+// Default returns the client behind the package-level functions.
+func Default() *Client {
+	if std == nil {
+		// No WithPublicKey: PinnedKeys falls back to PublicKey, and
+		// keys pinned with PinKeys have to win.
+		std = NewClient(Name, OwnerID, Version, WithTokenPath(TokenPath), WithAPIURL(APIUrl), WithRequireNonce(RequireNonce))
 	}
+	return std
+}
 
-	response := doRequest(postData)
+// syncGlobals mirrors the default client's state into the package
+// variables so code reading them keeps working.
+func syncGlobals() {
+	c := Default()
 
-	if response == "EpicAuth_Invalid" {
-		fmt.Println("The application does not exist.")
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.initialized {
+		SessionID = c.sessionID
 	}
+	Initialized = c.initialized
 
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
+	Username = c.user.Username
+	IP = c.user.IP
+	HWID = c.user.HWID.String()
+	if HWID == "" {
+		HWID = "N/A"
 	}
-
-	if jsonResponse["message"] == "invalidver" {
-		if jsonResponse["download"] != "" {
-			fmt.Println("New application version found! Downloading...")
-			downloadLink := jsonResponse["download"].(string)
-			openUrl(downloadLink)
-			time.Sleep(3 * time.Second)
-			os.Exit(1)
-		} else {
-			fmt.Println("Invalid application version, contact the owner to add the download link for the latest app version")
-			time.Sleep(3 * time.Second)
-			os.Exit(1)
-		}
+	CreatedDate = c.user.CreateDate.String()
+	LastLogin = c.user.LastLogin.String()
+	Expires, Subscription = "", ""
+	if len(c.user.Subscriptions) > 0 {
+		Expires = c.user.Subscriptions[0].Expiry.String()
+		Subscription = c.user.Subscriptions[0].Subscription
 	}
-
-	if !jsonResponse["success"].(bool) {
-		fmt.Println(jsonResponse["message"].(string))
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
+	Subscriptions = ""
+	if c.user.Subscriptions != nil {
+		subscriptionsJSON, _ := json.Marshal(c.user.Subscriptions)
+		Subscriptions = string(subscriptionsJSON)
 	}
 
-	SessionID = jsonResponse["sessionid"].(string)
-	Initialized = true
-
-	if jsonResponse["newSession"].(bool) {
-		time.Sleep(100 * time.Millisecond)
-	}
+	NumUsers = c.app.NumUsers.String()
+	NumOnlineUsers = c.app.NumOnlineUsers.String()
+	NumKeys = c.app.NumKeys.String()
+	CustomerPanelURL = c.app.CustomerPanelLink
 }
 
-func Register(user, password, license string) {
-	CheckInit()
-
-	hwid := GetHWID()
-
-	postData := map[string]string{
-		"type":      "register",
-		"username":  user,
-		"pass":      password,
-		"key":       license,
-		"hwid":      hwid,
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
+func Api(name, ownerid, version, path string) error {
+	Name = name
+	OwnerID = ownerid
+	Version = version
+	TokenPath = path
+	if path == "null" {
+		TokenPath = ""
 	}
 
-	if jsonResponse["success"].(bool) {
-		fmt.Println(jsonResponse["message"].(string))
-		LoadUserData(jsonResponse["info"])
-	} else {
-		fmt.Println(jsonResponse["message"].(string))
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
+	std = nil
+	return Init()
 }
 
-func Login(user, password string) {
-	CheckInit()
-
-	hwid := GetHWID()
-
-	postData := map[string]string{
-		"type":      "login",
-		"username":  user,
-		"pass":      password,
-		"hwid":      hwid,
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
+func Init() error {
+	if SessionID != "lol" {
+		return ErrAlreadyInitialized
 	}
 
-	if jsonResponse["success"].(bool) {
-		fmt.Println(jsonResponse["message"].(string))
-		LoadUserData(jsonResponse["info"])
-	} else {
-		fmt.Println(jsonResponse["message"].(string))
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
+	err := Default().Init()
+	syncGlobals()
+	return err
 }
 
-func Forgot(user, email string) {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":      "forgot",
-		"username":  user,
-		"email":     email,
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	if jsonResponse["success"].(bool) {
-		fmt.Println(jsonResponse["message"].(string))
-		LoadUserData(jsonResponse["info"])
-	} else {
-		fmt.Println(jsonResponse["message"].(string))
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
+func Register(user, password, license string) (*LoginResponse, error) {
+	data, err := Default().Register(user, password, license)
+	syncGlobals()
+	return data, err
 }
 
-func Upgrade(user, license string) {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":      "upgrade",
-		"username":  user,
-		"key":       license,
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	if jsonResponse["success"].(bool) {
-		fmt.Println(jsonResponse["message"].(string))
-		fmt.Println("Please restart the application and login again to see the changes.")
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	} else {
-		fmt.Println(jsonResponse["message"].(string))
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
+func Login(user, password string) (*LoginResponse, error) {
+	data, err := Default().Login(user, password)
+	syncGlobals()
+	return data, err
 }
 
-func License(key string) {
-	CheckInit()
-
-	hwid := GetHWID()
-
-	postData := map[string]string{
-		"type":      "license",
-		"key":       key,
-		"hwid":      hwid,
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	if jsonResponse["success"].(bool) {
-		LoadUserData(jsonResponse["info"])
-		fmt.Println(jsonResponse["message"].(string))
-	} else {
-		fmt.Println(jsonResponse["message"].(string))
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
+func Forgot(user, email string) (string, error) {
+	return Default().Forgot(user, email)
 }
 
-func Var(name string) string {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":      "var",
-		"varid":     name,
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	if jsonResponse["success"].(bool) {
-		return jsonResponse["message"].(string)
-	} else {
-		fmt.Println(jsonResponse["message"].(string))
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	return ""
+func Upgrade(user, license string) (string, error) {
+	return Default().Upgrade(user, license)
 }
 
-func GetVar(varName string) string {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":      "getvar",
-		"var":       varName,
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	if jsonResponse["success"].(bool) {
-		return jsonResponse["response"].(string)
-	} else {
-		fmt.Println("NOTE: This is commonly misunderstood. This is for user variables, not the normal variables.")
-		fmt.Println("Use EpicAuthApp.var(\"%s\") for normal variables." + varName)
-		fmt.Println(jsonResponse["message"].(string))
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	return ""
+func License(key string) (*LoginResponse, error) {
+	data, err := Default().License(key)
+	syncGlobals()
+	return data, err
 }
 
-func SetVar(varName, varData string) bool {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":      "setvar",
-		"var":       varName,
-		"data":      varData,
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	if jsonResponse["success"].(bool) {
-		return true
-	} else {
-		fmt.Println(jsonResponse["message"].(string))
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	return false
+func Var(name string) (string, error) {
+	return Default().Var(name)
 }
 
-func Ban() bool {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":      "ban",
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	if jsonResponse["success"].(bool) {
-		return true
-	} else {
-		fmt.Println(jsonResponse["message"].(string))
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	return false
+func GetVar(varName string) (string, error) {
+	return Default().GetVar(varName)
 }
 
-func Download(fileID string) []byte {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":      "file",
-		"fileid":    fileID,
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	if !jsonResponse["success"].(bool) {
-		fmt.Println(jsonResponse["message"].(string))
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	decodedContent, err := hex.DecodeString(jsonResponse["contents"].(string))
-	if err != nil {
-		fmt.Println("Error decoding file contents: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	return decodedContent
+func SetVar(varName, varData string) error {
+	return Default().SetVar(varName, varData)
 }
 
-func Webhook(webID, param, body, contType string) string {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":      "webhook",
-		"webid":     webID,
-		"params":    param,
-		"body":      body,
-		"conttype":  contType,
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	if jsonResponse["success"].(bool) {
-		return jsonResponse["message"].(string)
-	} else {
-		fmt.Println(jsonResponse["message"].(string))
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	return ""
+func Ban() error {
+	return Default().Ban()
 }
 
-func CheckBlack() bool {
-	CheckInit()
-	hwid := GetHWID()
-
-	postData := map[string]string{
-		"type":      "checkblacklist",
-		"hwid":      hwid,
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		return false
-	}
-
-	return jsonResponse["success"].(bool)
+func Download(fileID string) ([]byte, error) {
+	return Default().Download(fileID)
 }
 
-func Log(message string) {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":      "log",
-		"pcuser":    os.Getenv("username"),
-		"message":   message,
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-
-	doRequest(postData)
+func Webhook(webID, param, body, contType string) (string, error) {
+	return Default().Webhook(webID, param, body, contType)
 }
 
-func FetchOnline() []string {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":      "fetchOnline",
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		return nil
-	}
-
-	if jsonResponse["success"].(bool) {
-		users, ok := jsonResponse["users"].([]string)
-		if ok {
-			return users
-		}
-	}
-
-	return nil
+func CheckBlack() (bool, error) {
+	return Default().CheckBlack()
 }
 
-func FetchStats() {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":      "fetchStats",
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		return
-	}
-
-	if jsonResponse["success"].(bool) {
-		LoadAppData(jsonResponse["appinfo"])
-	}
+func Log(message string) error {
+	return Default().Log(message)
 }
 
-func Check() bool {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":      "check",
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	return jsonResponse["success"].(bool)
+func FetchOnline() ([]OnlineUser, error) {
+	return Default().FetchOnline()
 }
 
-func ChatGet(channel string) []string {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":      "chatget",
-		"channel":   channel,
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		return nil
-	}
-
-	if jsonResponse["success"].(bool) {
-		messages, ok := jsonResponse["messages"].([]string)
-		if ok {
-			return messages
-		}
-	}
-
-	return nil
+func FetchStats() (*AppStats, error) {
+	data, err := Default().FetchStats()
+	syncGlobals()
+	return data, err
 }
 
-func ChatSend(message, channel string) bool {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":      "chatsend",
-		"message":   message,
-		"channel":   channel,
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		return false
-	}
-
-	return jsonResponse["success"].(bool)
+func Check() (bool, error) {
+	return Default().Check()
 }
 
-func ChangeUsername(username string) {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":        "changeUsername",
-		"newUsername": username,
-		"sessionid":   SessionID,
-		"name":        Name,
-		"ownerid":     OwnerID,
-	}
-
-	response := doRequest(postData)
-
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	if jsonResponse["success"].(bool) {
-		fmt.Println("Successfully changed username")
-	} else {
-		fmt.Println(jsonResponse["message"].(string))
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
+func ChatGet(channel string) ([]ChatMessage, error) {
+	return Default().ChatGet(channel)
 }
 
-func Logout() {
-	CheckInit()
-
-	postData := map[string]string{
-		"type":      "logout",
-		"sessionid": SessionID,
-		"name":      Name,
-		"ownerid":   OwnerID,
-	}
-
-	response := doRequest(postData)
+func ChatSend(message, channel string) error {
+	return Default().ChatSend(message, channel)
+}
 
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonResponse); err != nil {
-		fmt.Println("Error decoding JSON response: " + err.Error())
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
+func ChangeUsername(username string) error {
+	return Default().ChangeUsername(username)
+}
 
-	if jsonResponse["success"].(bool) {
-		fmt.Println("Successfully logged out")
-	} else {
-		fmt.Println(jsonResponse["message"].(string))
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
+func Logout() error {
+	return Default().Logout()
 }
 
-func CheckInit() {
+func CheckInit() error {
 	if SessionID == "lol" {
-		fmt.Println("Please initialize the application before using any of its functions.")
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
+		return ErrNotInitialized
 	}
+	return nil
 }
 
-func IsEmpty() {
+func IsEmpty() error {
 	if Name == "" || OwnerID == "" || Secret == "" || Version == "" || len(OwnerID) != 10 || len(Secret) != 64 {
-		fmt.Println("Application not setup properlly.")
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
+		return ErrInvalidApp
 	}
+	return nil
 }
 
-func doRequest(postData map[string]string) string {
-	requestBody := url.Values{}
-	for key, value := range postData {
-		requestBody.Set(key, value)
+// LoadAppData stores an already decoded "appinfo" object as the default
+// client's app data.
+func LoadAppData(data interface{}) (*AppInfo, error) {
+	var app AppInfo
+	if err := redecode(data, &app); err != nil {
+		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", APIUrl, strings.NewReader(requestBody.Encode()))
-	if err != nil {
-		fmt.Println("Error creating request:", err)
-		return ""
-	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c := Default()
+	c.mu.Lock()
+	c.app = app
+	c.mu.Unlock()
+	syncGlobals()
+	return &app, nil
+}
 
-	client := http.Client{Timeout: 10 * time.Second}
-	response, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request:", err)
-		return ""
+// LoadUserData stores an already decoded "info" object as the default
+// client's user data.
+func LoadUserData(data interface{}) (*UserInfo, error) {
+	var user UserInfo
+	if err := redecode(data, &user); err != nil {
+		return nil, err
 	}
-	defer response.Body.Close()
 
-	responseBody, err := io.ReadAll(response.Body)
-	if err != nil {
-		fmt.Println("Error reading response:", err)
-		return ""
-	}
+	c := Default()
+	c.mu.Lock()
+	c.user = user
+	c.mu.Unlock()
+	syncGlobals()
+	return &user, nil
+}
 
-	signature := response.Header.Get("x-signature-ed25519")
-	timestamp := response.Header.Get("x-signature-timestamp")
-	if signature == "" || timestamp == "" {
-		fmt.Println("Missing signature or timestamp in response headers")
-		time.Sleep(5 * time.Second)
-		os.Exit(1)
+func redecode(data interface{}, out interface{}) error {
+	if data == nil {
+		return fmt.Errorf("%w: no data", ErrInvalidResponse)
 	}
-
-	serverTime, err := strconv.ParseInt(timestamp, 10, 64)
+	raw, err := json.Marshal(data)
 	if err != nil {
-		fmt.Println("Invalid timestamp format:", err)
-		time.Sleep(5 * time.Second)
-		os.Exit(1)
-	}
-	currentTime := time.Now().Unix()
-	bufferSeconds := int64(5)
-	if abs(currentTime-serverTime) > bufferSeconds+20 {
-		fmt.Printf("Time difference is too large: %d seconds, try syncing your date and time settings.\n", abs(currentTime-serverTime))
-		time.Sleep(5 * time.Second)
-		os.Exit(1)
+		return fmt.Errorf("%w: %v", ErrInvalidResponse, err)
 	}
-
-	if !verifySignature(responseBody, signature, timestamp, PublicKey) {
-		fmt.Println("Signature checksum failed. Request was tampered with or session ended most likely.")
-		time.Sleep(3 * time.Second)
-		os.Exit(1)
-	}
-
-	exeName := filepath.Base(os.Args[0])
-	debugPath := filepath.Join("C:\\ProgramData\\EpicAuth\\Debug", exeName)
-
-	if _, err := os.Stat(debugPath); os.IsNotExist(err) {
-		if err := os.MkdirAll(debugPath, 0755); err != nil {
-			fmt.Println("Error creating debug directory:", err)
-		}
-	}
-
-	if len(string(responseBody)) <= 200 {
-		tampered := false
-		executionTime := time.Now().Format("03:04:05 PM | 01/02/2006")
-
-		redactedResponse := redactFields(responseBody)
-
-		debugLog := fmt.Sprintf("\n%s | %s \nResponse: %s\nWas response tampered with? %v\n", executionTime, postData["type"], redactedResponse, tampered)
-
-		if err := writeDebugLogToFile(filepath.Join(debugPath, "log.txt"), debugLog); err != nil {
-			fmt.Println("Error writing debug log to file:", err)
-		}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidResponse, err)
 	}
-
-	return string(responseBody)
+	return nil
 }
 
 func verifySignature(responseBody []byte, signature, timestamp, publicKey string) bool {
-	message := append([]byte(timestamp), responseBody...)
-
-	signatureBytes, err := hex.DecodeString(signature)
-	if err != nil {
-		fmt.Println("Error decoding signature:", err)
-		return false
-	}
-
-	publicKeyBytes, err := hex.DecodeString(publicKey)
-	if err != nil {
-		fmt.Println("Error decoding public key:", err)
-		return false
-	}
-
-	verified := ed25519.Verify(publicKeyBytes, message, signatureBytes)
+	_, verified := publicKeySet("", publicKey).Verify(responseBody, signature, timestamp)
 	return verified
 }
 
@@ -834,44 +277,14 @@ func abs(x int64) int64 {
 	return x
 }
 
+// GetHWID returns the HWID from DefaultHWIDProvider, or "" if it can't be
+// read.
 func GetHWID() string {
-	switch runtime.GOOS {
-	case "linux":
-		out, err := exec.Command("cat", "/etc/machine-id").Output()
-		if err != nil {
-			fmt.Println("Error reading /etc/machine-id: " + err.Error())
-			return ""
-		}
-		hwid := string(out)
-		return hwid
-
-	case "windows":
-		const xx = "cmd.exe"
-
-		var stdout bytes.Buffer
-		cmd := exec.Command(xx, "/c", "wmic useraccount where name='%username%' get sid")
-		cmd.Stdout = &stdout
-		err := cmd.Run()
-		if err != nil {
-			return ""
-		}
-
-		return strings.TrimSpace(strings.TrimPrefix(stdout.String(), "SID"))
-
-	case "darwin":
-		out, err := exec.Command("ioreg", "-l", "|", "grep", "IOPlatformSerialNumber").Output()
-		if err != nil {
-			fmt.Println("Error reading IOPlatformSerialNumber: " + err.Error())
-			return ""
-		}
-		serial := strings.Split(string(out), "=")[1]
-		hwid := strings.TrimSpace(strings.ReplaceAll(serial, " ", ""))
-		return hwid
-
-	default:
-		fmt.Println("Unfortunatly you are on an unsupported OS.")
+	hwid, err := DefaultHWIDProvider().HWID()
+	if err != nil {
 		return ""
 	}
+	return hwid
 }
 
 func checkSum(filename string) string {
@@ -883,64 +296,16 @@ func checkSum(filename string) string {
 
 	hash := md5.New()
 	if _, err := io.Copy(hash, file); err != nil {
-		panic(err)
+		return ""
 	}
 
 	hashInBytes := hash.Sum(nil)
 	return hex.EncodeToString(hashInBytes)
 }
 
-func LoadAppData(data interface{}) string {
-	appInfo, ok := data.(map[string]interface{})
-	if !ok {
-		return "Error: AppInfo data is not in expected format"
-	}
-	NumUsers = appInfo["numUsers"].(string)
-	NumKeys = appInfo["numKeys"].(string)
-	CustomerPanelURL = appInfo["customerPanelLink"].(string)
-	NumOnlineUsers = appInfo["numOnlineUsers"].(string)
-
-	return ""
-}
-
-func LoadUserData(data interface{}) string {
-	userInfo, ok := data.(map[string]interface{})
-	if !ok {
-		return "Error: UserInfo data is not in expected format"
-	}
-
-	Username = userInfo["username"].(string)
-	IP = userInfo["ip"].(string)
-
-	if hwidFloat, ok := userInfo["hwid"].(float64); ok {
-		HWID = fmt.Sprintf("%f", hwidFloat)
-	} else {
-		HWID = userInfo["hwid"].(string)
-	}
-	if HWID == "" {
-		HWID = "N/A"
-	}
-
-	subscriptions, ok := userInfo["subscriptions"].([]interface{})
-	if ok && len(subscriptions) > 0 {
-		subscriptionData, ok := subscriptions[0].(map[string]interface{})
-		if ok {
-			Expires = subscriptionData["expiry"].(string)
-			Subscription = subscriptionData["subscription"].(string)
-		}
-	}
-
-	CreatedDate = userInfo["createdate"].(string)
-	LastLogin = userInfo["lastlogin"].(string)
-	subscriptionsJSON, err := json.Marshal(subscriptions)
-	if err != nil {
-		return "Error converting subscriptions to JSON: " + err.Error()
-	}
-	Subscriptions = string(subscriptionsJSON)
-	return ""
-}
-
-func openUrl(url string) error {
+// OpenURL opens url in the default browser, e.g. the Download link of an
+// ErrInvalidVersion error.
+func OpenURL(url string) error {
 	var cmd string
 	var args []string
 
@@ -956,52 +321,3 @@ func openUrl(url string) error {
 	args = append(args, url)
 	return exec.Command(cmd, args...).Start()
 }
-
-func writeDebugLogToFile(filePath, debugLog string) error {
-	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	_, err = file.WriteString(debugLog)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func tokenHash(tokenPath string) string {
-	data, err := ioutil.ReadFile(tokenPath)
-	if err != nil {
-		panic(err)
-	}
-
-	hash := sha256.Sum256(data)
-	return hex.EncodeToString(hash[:])
-}
-
-func redactFields(responseBody []byte) string {
-	var responseMap map[string]interface{}
-	if err := json.Unmarshal(responseBody, &responseMap); err != nil {
-		fmt.Println("Error unmarshalling response:", err)
-		return string(responseBody)
-	}
-
-	sensitiveFields := []string{"sessionid", "ownerid", "app", "secret", "version", "fileid", "webhooks"}
-
-	for _, field := range sensitiveFields {
-		if _, exists := responseMap[field]; exists {
-			responseMap[field] = "REDACTED"
-		}
-	}
-
-	redactedResponse, err := json.Marshal(responseMap)
-	if err != nil {
-		fmt.Println("Error marshalling redacted response:", err)
-		return string(responseBody)
-	}
-
-	return string(redactedResponse)
-}
@@ -0,0 +1,365 @@
+package EpicAuth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+
+	body := []byte(`{"success":true,"message":"Initialized"}`)
+	timestamp := "1700000000"
+	signature := hex.EncodeToString(ed25519.Sign(priv, append([]byte(timestamp), body...)))
+
+	tests := []struct {
+		name      string
+		body      []byte
+		signature string
+		timestamp string
+		publicKey string
+		want      bool
+	}{
+		{"valid", body, signature, timestamp, hex.EncodeToString(pub), true},
+		{"tampered body", []byte(`{"success":false,"message":"Initialized"}`), signature, timestamp, hex.EncodeToString(pub), false},
+		{"tampered timestamp", body, signature, "1700000001", hex.EncodeToString(pub), false},
+		{"wrong key", body, signature, timestamp, hex.EncodeToString(otherPub), false},
+		{"bad signature hex", body, "not-hex", timestamp, hex.EncodeToString(pub), false},
+		{"truncated signature", body, signature[:64], timestamp, hex.EncodeToString(pub), false},
+		{"empty signature", body, "", timestamp, hex.EncodeToString(pub), false},
+		{"bad key hex", body, signature, timestamp, "zz", false},
+		{"short key", body, signature, timestamp, hex.EncodeToString(pub[:16]), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifySignature(tt.body, tt.signature, tt.timestamp, tt.publicKey); got != tt.want {
+				t.Errorf("verifySignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// signedTransport answers every request with body, signed at the given
+// offset from now.
+// signedTransport answers every request with body, signed by priv at
+// now+offset. JSON object bodies echo the request nonce.
+func signedTransport(priv ed25519.PrivateKey, now time.Time, offset time.Duration, body string) Transport {
+	return TransportFunc(func(ctx context.Context, form url.Values) (*RawResponse, error) {
+		body := body
+		if nonce := form.Get("nonce"); strings.HasPrefix(body, "{") && nonce != "" {
+			echo := `"nonce":"` + nonce + `"`
+			if body != "{}" {
+				echo += ","
+			}
+			body = "{" + echo + body[1:]
+		}
+		timestamp := strconv.FormatInt(now.Add(offset).Unix(), 10)
+		return &RawResponse{
+			StatusCode: 200,
+			Body:       []byte(body),
+			Signature:  hex.EncodeToString(ed25519.Sign(priv, append([]byte(timestamp), body...))),
+			Timestamp:  timestamp,
+		}, nil
+	})
+}
+
+func TestDoRequestClockSkew(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		offset  time.Duration
+		wantErr error
+	}{
+		{0, nil},
+		{25 * time.Second, nil},
+		{-25 * time.Second, nil},
+		{26 * time.Second, ErrClockSkew},
+		{-26 * time.Second, ErrClockSkew},
+		{time.Hour, ErrClockSkew},
+	}
+	for _, tt := range tests {
+		t.Run(tt.offset.String(), func(t *testing.T) {
+			c := NewClient("test", "abcdefghij", "1.0",
+				WithPublicKey(hex.EncodeToString(pub)),
+				WithTransport(signedTransport(priv, now, tt.offset, `{"success":true}`)),
+				WithLogger(nil),
+			)
+			c.now = func() time.Time { return now }
+
+			_, err := c.doRequest(context.Background(), map[string]string{"type": "check"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("doRequest() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDoRequestRejectsBadHeaders(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	body := []byte(`{"success":true}`)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := hex.EncodeToString(ed25519.Sign(priv, append([]byte(timestamp), body...)))
+
+	tests := []struct {
+		name     string
+		response RawResponse
+	}{
+		{"missing signature", RawResponse{Body: body, Timestamp: timestamp}},
+		{"missing timestamp", RawResponse{Body: body, Signature: signature}},
+		{"non numeric timestamp", RawResponse{Body: body, Signature: signature, Timestamp: "soon"}},
+		{"tampered body", RawResponse{Body: []byte(`{"success":false}`), Signature: signature, Timestamp: timestamp}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := tt.response
+			c := NewClient("test", "abcdefghij", "1.0",
+				WithPublicKey(hex.EncodeToString(pub)),
+				WithTransport(TransportFunc(func(ctx context.Context, form url.Values) (*RawResponse, error) {
+					return &response, nil
+				})),
+				WithLogger(nil),
+			)
+
+			_, err := c.doRequest(context.Background(), map[string]string{"type": "check"})
+			if !errors.Is(err, ErrSignatureInvalid) {
+				t.Errorf("doRequest() error = %v, want %v", err, ErrSignatureInvalid)
+			}
+		})
+	}
+}
+
+func TestDoRequestNonce(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	sign := func(body string) *RawResponse {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		return &RawResponse{
+			StatusCode: 200,
+			Body:       []byte(body),
+			Signature:  hex.EncodeToString(ed25519.Sign(priv, append([]byte(timestamp), body...))),
+			Timestamp:  timestamp,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		body         func(nonce string) string
+		requireNonce bool
+		wantErr      error
+	}{
+		{"echoed", func(nonce string) string { return `{"success":true,"nonce":"` + nonce + `"}` }, true, nil},
+		{"missing", func(string) string { return `{"success":true}` }, true, ErrNonceMismatch},
+		{"missing allowed", func(string) string { return `{"success":true}` }, false, nil},
+		{"wrong", func(string) string { return `{"success":true,"nonce":"0123"}` }, true, ErrNonceMismatch},
+		{"wrong when not required", func(string) string { return `{"success":true,"nonce":"0123"}` }, false, ErrNonceMismatch},
+		{"not json", func(string) string { return "EpicAuth_Invalid" }, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient("test", "abcdefghij", "1.0",
+				WithPublicKey(hex.EncodeToString(pub)),
+				WithTransport(TransportFunc(func(ctx context.Context, form url.Values) (*RawResponse, error) {
+					return sign(tt.body(form.Get("nonce"))), nil
+				})),
+				WithRequireNonce(tt.requireNonce),
+				WithLogger(nil),
+			)
+
+			_, err := c.doRequest(context.Background(), map[string]string{"type": "check"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("doRequest() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("replayed", func(t *testing.T) {
+		var captured *RawResponse
+		c := NewClient("test", "abcdefghij", "1.0",
+			WithPublicKey(hex.EncodeToString(pub)),
+			WithTransport(TransportFunc(func(ctx context.Context, form url.Values) (*RawResponse, error) {
+				if captured == nil {
+					captured = sign(`{"success":true,"nonce":"` + form.Get("nonce") + `"}`)
+				}
+				return captured, nil
+			})),
+			WithLogger(nil),
+		)
+
+		if _, err := c.doRequest(context.Background(), map[string]string{"type": "check"}); err != nil {
+			t.Fatalf("first doRequest() error = %v", err)
+		}
+		if _, err := c.doRequest(context.Background(), map[string]string{"type": "check"}); !errors.Is(err, ErrNonceMismatch) {
+			t.Errorf("replayed doRequest() error = %v, want %v", err, ErrNonceMismatch)
+		}
+	})
+}
+
+func TestDefaultRequireNonce(t *testing.T) {
+	saved := std
+	defer func() {
+		std, RequireNonce = saved, false
+	}()
+
+	std = nil
+	if Default().requireNonce {
+		t.Error("default client requires nonces")
+	}
+	std, RequireNonce = nil, true
+	if !Default().requireNonce {
+		t.Error("default client ignores RequireNonce")
+	}
+}
+
+func TestTokenHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.txt")
+	token := []byte("my-token\n")
+	if err := os.WriteFile(path, token, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient("test", "abcdefghij", "1.0", WithTokenPath(path), WithLogger(nil))
+	read, err := c.readToken()
+	if err != nil || string(read) != string(token) {
+		t.Fatalf("readToken() = %q, %v", read, err)
+	}
+	sum := sha256.Sum256(token)
+	if got, want := tokenHash(read), hex.EncodeToString(sum[:]); got != want {
+		t.Errorf("tokenHash() = %s, want %s", got, want)
+	}
+}
+
+func TestRedactFields(t *testing.T) {
+	sensitive := []string{"sessionid", "ownerid", "app", "secret", "version", "fileid", "webhooks"}
+
+	for _, field := range sensitive {
+		t.Run(field, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{field: "leak", "message": "kept"})
+
+			var got map[string]interface{}
+			if err := json.Unmarshal([]byte(DefaultRedactor().RedactJSON(body)), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got[field] != "REDACTED" {
+				t.Errorf("%s = %v, want REDACTED", field, got[field])
+			}
+			if got["message"] != "kept" {
+				t.Errorf("message = %v, want kept", got["message"])
+			}
+		})
+	}
+
+	t.Run("not json", func(t *testing.T) {
+		if got := DefaultRedactor().RedactJSON([]byte("EpicAuth_Invalid")); got != "EpicAuth_Invalid" {
+			t.Errorf("RedactJSON() = %q", got)
+		}
+	})
+}
+
+func TestLoadUserData(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    UserInfo
+		wantErr bool
+	}{
+		{
+			name:    "full",
+			payload: `{"username":"bob","ip":"1.2.3.4","hwid":"abc","createdate":"1","lastlogin":"2","subscriptions":[{"subscription":"default","key":"K","expiry":"3","timeleft":10}]}`,
+			want: UserInfo{Username: "bob", IP: "1.2.3.4", HWID: "abc", CreateDate: "1", LastLogin: "2",
+				Subscriptions: []SubscriptionInfo{{Subscription: "default", Key: "K", Expiry: "3", TimeLeft: "10"}}},
+		},
+		{
+			name:    "numeric hwid and dates",
+			payload: `{"username":"bob","hwid":12345,"createdate":1,"lastlogin":2}`,
+			want:    UserInfo{Username: "bob", HWID: "12345", CreateDate: "1", LastLogin: "2"},
+		},
+		{
+			name:    "null fields",
+			payload: `{"username":"bob","ip":null,"hwid":null,"subscriptions":null}`,
+			want:    UserInfo{Username: "bob"},
+		},
+		{
+			name:    "missing and unknown fields",
+			payload: `{"username":"bob","favouriteColour":"blue"}`,
+			want:    UserInfo{Username: "bob"},
+		},
+		{name: "subscriptions not a list", payload: `{"username":"bob","subscriptions":"none"}`, wantErr: true},
+		{name: "hwid is an object", payload: `{"username":"bob","hwid":{"a":1}}`, wantErr: true},
+		{name: "not an object", payload: `"bob"`, wantErr: true},
+		{name: "null", payload: `null`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data interface{}
+			if err := json.Unmarshal([]byte(tt.payload), &data); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := LoadUserData(data)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidResponse) {
+					t.Fatalf("LoadUserData() error = %v, want %v", err, ErrInvalidResponse)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadUserData() error = %v", err)
+			}
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(tt.want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("LoadUserData() = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestLoadAppData(t *testing.T) {
+	var data interface{}
+	json.Unmarshal([]byte(`{"numUsers":"3","numOnlineUsers":1,"customerPanelLink":"https://example.com/"}`), &data)
+
+	got, err := LoadAppData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.NumUsers != "3" || got.NumOnlineUsers != "1" || got.NumKeys != "" || got.CustomerPanelLink != "https://example.com/" {
+		t.Errorf("LoadAppData() = %+v", got)
+	}
+	if NumOnlineUsers != "1" {
+		t.Errorf("NumOnlineUsers = %q, want 1", NumOnlineUsers)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		message string
+		want    error
+	}{
+		{"invalidver", ErrInvalidVersion},
+		{"HWID doesn't match. Ask for a HWID reset", ErrHWIDMismatch},
+		{"The user is banned", ErrBanned},
+		{"You've been blacklisted from this application", ErrBanned},
+		{"Client is not blacklisted", nil},
+		{"Session not found. Use latest client.", ErrSessionExpired},
+		{"Session is not validated", ErrSessionExpired},
+		{"Invalid token", ErrTokenRejected},
+		{"Invalid username or password", nil},
+	}
+	for _, tt := range tests {
+		if got := classify(tt.message); got != tt.want {
+			t.Errorf("classify(%q) = %v, want %v", tt.message, got, tt.want)
+		}
+	}
+}
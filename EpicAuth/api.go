@@ -0,0 +1,607 @@
+package EpicAuth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func (c *Client) Init() error {
+	return c.InitContext(context.Background())
+}
+
+// InitContext is like Init but uses ctx for the request.
+func (c *Client) InitContext(ctx context.Context) error {
+	if c.name == "" || c.ownerID == "" || c.version == "" || len(c.ownerID) != 10 {
+		return ErrInvalidApp
+	}
+	if c.Initialized() {
+		return ErrAlreadyInitialized
+	}
+
+	var token []byte
+	if c.token != nil {
+		var err error
+		if token, err = c.readToken(); err != nil {
+			return err
+		}
+	}
+
+	sessionID, err := c.initSession(ctx, token)
+	if err != nil {
+		if c.offline.enabled() && unreachable(err) {
+			if _, ok := c.OfflineGrace(); ok {
+				c.goOffline()
+				return nil
+			}
+		}
+		return err
+	}
+
+	c.mu.Lock()
+	c.sessionID = sessionID
+	c.initialized = true
+	c.tokenHash = ""
+	if token != nil {
+		c.tokenHash = tokenHash(token)
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// initSession sends an init request with token, if not nil, and returns
+// the new session's ID. It leaves the client's current session alone.
+func (c *Client) initSession(ctx context.Context, token []byte) (string, error) {
+	postData := map[string]string{
+		"type":    "init",
+		"ver":     c.version,
+		"hash":    checkSum(filepath.Base(os.Args[0])),
+		"name":    c.name,
+		"ownerid": c.ownerID,
+	}
+	if token != nil {
+		postData["token"] = string(token)
+		postData["thash"] = tokenHash(token)
+	}
+
+	var response InitResponse
+	if err := c.call(ctx, postData, &response); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.Err == ErrInvalidVersion {
+			apiErr.Download = response.Download
+		}
+		return "", err
+	}
+	if response.SessionID == "" {
+		return "", fmt.Errorf("%w: init response has no session id", ErrInvalidResponse)
+	}
+
+	if response.NewSession {
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+		}
+	}
+	return response.SessionID, nil
+}
+
+func (c *Client) Register(user, password, license string) (*LoginResponse, error) {
+	return c.RegisterContext(context.Background(), user, password, license)
+}
+
+// RegisterContext is like Register but uses ctx for the request.
+func (c *Client) RegisterContext(ctx context.Context, user, password, license string) (*LoginResponse, error) {
+	if err := c.CheckInit(); err != nil {
+		return nil, err
+	}
+	hwid, err := c.HWID()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.login(ctx, c.session(map[string]string{
+		"type":     "register",
+		"username": user,
+		"pass":     password,
+		"key":      license,
+		"hwid":     hwid,
+	}))
+}
+
+func (c *Client) Login(user, password string) (*LoginResponse, error) {
+	return c.LoginContext(context.Background(), user, password)
+}
+
+// LoginContext is like Login but uses ctx for the request.
+func (c *Client) LoginContext(ctx context.Context, user, password string) (*LoginResponse, error) {
+	if err := c.CheckInit(); err != nil {
+		return nil, err
+	}
+	hwid, err := c.HWID()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.login(ctx, c.session(map[string]string{
+		"type":     "login",
+		"username": user,
+		"pass":     password,
+		"hwid":     hwid,
+	}))
+}
+
+// Forgot asks the server to send a password reset email and returns the
+// server's message.
+func (c *Client) Forgot(user, email string) (string, error) {
+	return c.ForgotContext(context.Background(), user, email)
+}
+
+// ForgotContext is like Forgot but uses ctx for the request.
+func (c *Client) ForgotContext(ctx context.Context, user, email string) (string, error) {
+	if err := c.CheckInit(); err != nil {
+		return "", err
+	}
+
+	var response Status
+	err := c.call(ctx, c.session(map[string]string{
+		"type":     "forgot",
+		"username": user,
+		"email":    email,
+	}), &response)
+	return response.Message, err
+}
+
+// Upgrade redeems license on the user's account and returns the server's
+// message. The user should log in again to see the changes.
+func (c *Client) Upgrade(user, license string) (string, error) {
+	return c.UpgradeContext(context.Background(), user, license)
+}
+
+// UpgradeContext is like Upgrade but uses ctx for the request.
+func (c *Client) UpgradeContext(ctx context.Context, user, license string) (string, error) {
+	if err := c.CheckInit(); err != nil {
+		return "", err
+	}
+
+	var response Status
+	err := c.call(ctx, c.session(map[string]string{
+		"type":     "upgrade",
+		"username": user,
+		"key":      license,
+	}), &response)
+	return response.Message, err
+}
+
+func (c *Client) License(key string) (*LoginResponse, error) {
+	return c.LicenseContext(context.Background(), key)
+}
+
+// LicenseContext is like License but uses ctx for the request.
+func (c *Client) LicenseContext(ctx context.Context, key string) (*LoginResponse, error) {
+	if err := c.CheckInit(); err != nil {
+		return nil, err
+	}
+	hwid, err := c.HWID()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.login(ctx, c.session(map[string]string{
+		"type": "license",
+		"key":  key,
+		"hwid": hwid,
+	}))
+}
+
+// Var returns an application variable. With WithVarCache, variables are
+// read from the server once per TTL.
+func (c *Client) Var(name string) (string, error) {
+	return c.VarContext(context.Background(), name)
+}
+
+// VarContext is like Var but uses ctx for the request.
+func (c *Client) VarContext(ctx context.Context, name string) (string, error) {
+	if err := c.CheckInit(); err != nil {
+		return "", err
+	}
+	if c.vars != nil {
+		if value, ok := c.vars.get(name, c.now()); ok {
+			return value, nil
+		}
+	}
+
+	var response VarResponse
+	if err := c.call(ctx, c.session(map[string]string{
+		"type":  "var",
+		"varid": name,
+	}), &response); err != nil {
+		return "", err
+	}
+	if c.vars != nil {
+		c.vars.put(name, response.Message, c.now())
+	}
+	return response.Message, nil
+}
+
+// GetVar returns a user variable. Use Var for application variables.
+func (c *Client) GetVar(varName string) (string, error) {
+	return c.GetVarContext(context.Background(), varName)
+}
+
+// GetVarContext is like GetVar but uses ctx for the request.
+func (c *Client) GetVarContext(ctx context.Context, varName string) (string, error) {
+	if err := c.CheckInit(); err != nil {
+		return "", err
+	}
+
+	var response VarResponse
+	if err := c.call(ctx, c.session(map[string]string{
+		"type": "getvar",
+		"var":  varName,
+	}), &response); err != nil {
+		return "", err
+	}
+	return response.Response, nil
+}
+
+func (c *Client) SetVar(varName, varData string) error {
+	return c.SetVarContext(context.Background(), varName, varData)
+}
+
+// SetVarContext is like SetVar but uses ctx for the request.
+func (c *Client) SetVarContext(ctx context.Context, varName, varData string) error {
+	if err := c.CheckInit(); err != nil {
+		return err
+	}
+
+	return c.call(ctx, c.session(map[string]string{
+		"type": "setvar",
+		"var":  varName,
+		"data": varData,
+	}), &Status{})
+}
+
+func (c *Client) Ban() error {
+	return c.BanContext(context.Background())
+}
+
+// BanContext is like Ban but uses ctx for the request.
+func (c *Client) BanContext(ctx context.Context) error {
+	if err := c.CheckInit(); err != nil {
+		return err
+	}
+
+	return c.call(ctx, c.session(map[string]string{
+		"type": "ban",
+	}), &Status{})
+}
+
+// Download returns a file's contents. Use DownloadTo or DownloadFile for
+// large files.
+func (c *Client) Download(fileID string) ([]byte, error) {
+	return c.DownloadContext(context.Background(), fileID)
+}
+
+// DownloadContext is like Download but uses ctx for the request.
+func (c *Client) DownloadContext(ctx context.Context, fileID string) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := c.DownloadTo(ctx, fileID, &buf, DownloadOptions{}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Webhook sends a request to a webhook and returns the webhook's response
+// body. param is appended to the webhook's URL as is. SendWebhook builds
+// the parameters and body for you.
+func (c *Client) Webhook(webID, param, body, contType string) (string, error) {
+	return c.WebhookContext(context.Background(), webID, param, body, contType)
+}
+
+// WebhookContext is like Webhook but uses ctx for the request.
+func (c *Client) WebhookContext(ctx context.Context, webID, param, body, contType string) (string, error) {
+	if err := c.CheckInit(); err != nil {
+		return "", err
+	}
+
+	var response WebhookResponse
+	if err := c.call(ctx, c.session(map[string]string{
+		"type":     "webhook",
+		"webid":    webID,
+		"params":   param,
+		"body":     body,
+		"conttype": contType,
+	}), &response); err != nil {
+		return "", err
+	}
+	return response.Body(), nil
+}
+
+// CheckBlack reports whether the HWID or IP address is blacklisted.
+func (c *Client) CheckBlack() (bool, error) {
+	return c.CheckBlackContext(context.Background())
+}
+
+// CheckBlackContext is like CheckBlack but uses ctx for the request.
+func (c *Client) CheckBlackContext(ctx context.Context) (bool, error) {
+	if err := c.CheckInit(); err != nil {
+		return false, err
+	}
+	hwid, err := c.HWID()
+	if err != nil {
+		return false, err
+	}
+
+	var response Status
+	err = c.call(ctx, c.session(map[string]string{
+		"type": "checkblacklist",
+		"hwid": hwid,
+	}), &response)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Err == nil {
+		// An unclassified success=false here just means "not
+		// blacklisted"; expired sessions and the like are still errors.
+		return false, nil
+	}
+	return response.Success, err
+}
+
+func (c *Client) Log(message string) error {
+	return c.LogContext(context.Background(), message)
+}
+
+// LogContext is like Log but uses ctx for the request.
+func (c *Client) LogContext(ctx context.Context, message string) error {
+	if err := c.CheckInit(); err != nil {
+		return err
+	}
+
+	_, err := c.doRequest(ctx, c.session(map[string]string{
+		"type":    "log",
+		"pcuser":  os.Getenv("username"),
+		"message": message,
+	}))
+	return err
+}
+
+// FetchOnline returns the users with an active session.
+func (c *Client) FetchOnline() ([]OnlineUser, error) {
+	return c.FetchOnlineContext(context.Background())
+}
+
+// FetchOnlineContext is like FetchOnline but uses ctx for the request.
+func (c *Client) FetchOnlineContext(ctx context.Context) ([]OnlineUser, error) {
+	if err := c.CheckInit(); err != nil {
+		return nil, err
+	}
+
+	var response OnlineUsersResponse
+	err := c.call(ctx, c.session(map[string]string{
+		"type": "fetchOnline",
+	}), &response)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && strings.Contains(strings.ToLower(apiErr.Message), "no online users") {
+		// The server reports an empty list as a failure.
+		return []OnlineUser{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response.Users, nil
+}
+
+// FetchStats returns the application's statistics.
+func (c *Client) FetchStats() (*AppStats, error) {
+	return c.FetchStatsContext(context.Background())
+}
+
+// FetchStatsContext is like FetchStats but uses ctx for the request.
+func (c *Client) FetchStatsContext(ctx context.Context) (*AppStats, error) {
+	if err := c.CheckInit(); err != nil {
+		return nil, err
+	}
+
+	var response StatsResponse
+	if err := c.call(ctx, c.session(map[string]string{
+		"type": "fetchStats",
+	}), &response); err != nil {
+		return nil, err
+	}
+	if response.AppInfo == nil {
+		return nil, fmt.Errorf("%w: fetchStats response has no appinfo", ErrInvalidResponse)
+	}
+	stats, err := response.AppInfo.Stats()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.app = *response.AppInfo
+	c.mu.Unlock()
+	return &stats, nil
+}
+
+// Check reports whether the session is still valid. When it is not, the
+// returned error carries the server's reason.
+func (c *Client) Check() (bool, error) {
+	return c.CheckContext(context.Background())
+}
+
+// CheckContext is like Check but uses ctx for the request.
+func (c *Client) CheckContext(ctx context.Context) (bool, error) {
+	if err := c.CheckInit(); err != nil {
+		return false, err
+	}
+
+	if err := c.call(ctx, c.session(map[string]string{
+		"type": "check",
+	}), &Status{}); err != nil {
+		if errors.Is(err, ErrOffline) {
+			if _, ok := c.OfflineGrace(); !ok {
+				return false, ErrGraceExpired
+			}
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *Client) ChatGet(channel string) ([]ChatMessage, error) {
+	return c.ChatGetContext(context.Background(), channel)
+}
+
+// ChatGetContext is like ChatGet but uses ctx for the request.
+func (c *Client) ChatGetContext(ctx context.Context, channel string) ([]ChatMessage, error) {
+	if err := c.CheckInit(); err != nil {
+		return nil, err
+	}
+
+	var response ChatMessagesResponse
+	if err := c.call(ctx, c.session(map[string]string{
+		"type":    "chatget",
+		"channel": channel,
+	}), &response); err != nil {
+		return nil, err
+	}
+	return response.Messages, nil
+}
+
+// ChatSend sends a message to a chat channel. It returns a *ChatError if
+// the user is muted or sends faster than WithChatInterval allows, in
+// which case the message isn't sent to the server at all.
+func (c *Client) ChatSend(message, channel string) error {
+	return c.ChatSendContext(context.Background(), message, channel)
+}
+
+// ChatSendContext is like ChatSend but uses ctx for the request.
+func (c *Client) ChatSendContext(ctx context.Context, message, channel string) error {
+	if err := c.CheckInit(); err != nil {
+		return err
+	}
+	if wait := c.chat.reserve(channel, c.now()); wait > 0 {
+		return &ChatError{Channel: channel, RetryAfter: wait, Err: ErrRateLimited}
+	}
+
+	err := c.call(ctx, c.session(map[string]string{
+		"type":    "chatsend",
+		"message": message,
+		"channel": channel,
+	}), &Status{})
+	switch {
+	case errors.Is(err, ErrRateLimited):
+		return &ChatError{Channel: channel, RetryAfter: c.chat.backOff(channel, c.now()), Err: err}
+	case errors.Is(err, ErrMuted):
+		return &ChatError{Channel: channel, Err: err}
+	}
+	return err
+}
+
+func (c *Client) ChangeUsername(username string) error {
+	return c.ChangeUsernameContext(context.Background(), username)
+}
+
+// ChangeUsernameContext is like ChangeUsername but uses ctx for the request.
+func (c *Client) ChangeUsernameContext(ctx context.Context, username string) error {
+	if err := c.CheckInit(); err != nil {
+		return err
+	}
+
+	return c.call(ctx, c.session(map[string]string{
+		"type":        "changeUsername",
+		"newUsername": username,
+	}), &Status{})
+}
+
+// Logout ends the session and stops the heartbeat, if one is running.
+func (c *Client) Logout() error {
+	return c.LogoutContext(context.Background())
+}
+
+// LogoutContext is like Logout but uses ctx for the request.
+func (c *Client) LogoutContext(ctx context.Context) error {
+	if err := c.CheckInit(); err != nil {
+		return err
+	}
+	c.stopHeartbeat()
+	c.mu.Lock()
+	c.offlineLogin = nil
+	c.mu.Unlock()
+
+	return c.call(ctx, c.session(map[string]string{
+		"type": "logout",
+	}), &Status{})
+}
+
+// call sends postData and decodes the response into out, returning an
+// *APIError if the server reports a failure.
+func (c *Client) call(ctx context.Context, postData map[string]string, out statusReporter) error {
+	response, err := c.doRequest(ctx, postData)
+	if err != nil {
+		return err
+	}
+	return decode(postData["type"], response.Body, out)
+}
+
+// decode decodes the response body of a requestType request into out.
+func decode(requestType string, body []byte, out statusReporter) error {
+	if string(body) == "EpicAuth_Invalid" {
+		return &APIError{Type: requestType, Message: "The application does not exist.", Err: ErrInvalidApp}
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+
+	if status := out.status(); !status.Success {
+		return newAPIError(requestType, status.Message)
+	}
+	return nil
+}
+
+// login performs one of the calls that return user info and stores it.
+// If the server can't be reached, a cached login may answer this call
+// instead; see WithOfflineCache.
+func (c *Client) login(ctx context.Context, postData map[string]string) (*LoginResponse, error) {
+	raw, err := c.doRequest(ctx, postData)
+	if err != nil {
+		if c.offline.enabled() && (unreachable(err) || errors.Is(err, ErrOffline)) {
+			response, offlineErr := c.loginOffline(postData)
+			if offlineErr != errNoOfflineLogin {
+				return response, offlineErr
+			}
+		}
+		return nil, err
+	}
+
+	response, err := decodeLogin(postData["type"], raw.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.user = *response.Info
+	c.mu.Unlock()
+
+	if c.offline.enabled() {
+		// A cache that can't be written only costs the offline fallback.
+		_ = c.saveOffline(postData, raw)
+	}
+	return response, nil
+}
+
+func decodeLogin(requestType string, body []byte) (*LoginResponse, error) {
+	var response LoginResponse
+	if err := decode(requestType, body, &response); err != nil {
+		return nil, err
+	}
+	if response.Info == nil {
+		return nil, fmt.Errorf("%w: %s response has no user info", ErrInvalidResponse, requestType)
+	}
+	return &response, nil
+}
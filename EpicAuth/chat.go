@@ -0,0 +1,154 @@
+package EpicAuth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultChatInterval is the least time between two messages to one
+// channel unless WithChatInterval says otherwise.
+const DefaultChatInterval = time.Second
+
+// ChatError is returned by ChatSend when a message isn't sent because
+// the user is muted or sending too fast. Err is ErrRateLimited if the
+// client held the message back, or the server's *APIError, which wraps
+// ErrMuted or ErrRateLimited.
+type ChatError struct {
+	Channel string
+	// RetryAfter is how long to wait before sending to Channel again.
+	// It is zero for ErrMuted.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ChatError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("EpicAuth: chat channel %s: %v, retry in %v", e.Channel, e.Err, e.RetryAfter)
+	}
+	return fmt.Sprintf("EpicAuth: chat channel %s: %v", e.Channel, e.Err)
+}
+
+func (e *ChatError) Unwrap() error {
+	return e.Err
+}
+
+// chatThrottle spaces out messages to each channel by interval.
+type chatThrottle struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+// reserve claims a send to channel at now, or returns how long to wait
+// if it's too soon.
+func (t *chatThrottle) reserve(channel string, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if wait := t.next[channel].Sub(now); wait > 0 {
+		return wait
+	}
+	if t.interval <= 0 {
+		return 0
+	}
+	if t.next == nil {
+		t.next = make(map[string]time.Time)
+	}
+	t.next[channel] = now.Add(t.interval)
+	return 0
+}
+
+// backOff holds back sends to channel after the server said they were
+// too fast, and returns for how long.
+func (t *chatThrottle) backOff(channel string, now time.Time) time.Duration {
+	wait := t.interval
+	if wait <= 0 {
+		wait = DefaultChatInterval
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.next == nil {
+		t.next = make(map[string]time.Time)
+	}
+	t.next[channel] = now.Add(wait)
+	return wait
+}
+
+// ChatSubscription polls a chat channel for new messages. Create one
+// with Client.SubscribeChat.
+type ChatSubscription struct {
+	*poller
+	c        *Client
+	channel  string
+	messages chan ChatMessage
+
+	// seen counts the messages of the last poll, since the server only
+	// returns the most recent ones.
+	seen map[ChatMessage]int
+}
+
+// SubscribeChat polls channel every interval until ctx is done, Stop is
+// called or the session turns out invalid, and sends every new message
+// to Messages. Messages already in the channel when it starts aren't
+// sent; use ChatGet for those.
+func (c *Client) SubscribeChat(ctx context.Context, channel string, interval time.Duration) (*ChatSubscription, error) {
+	if err := c.CheckInit(); err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	messages, err := c.ChatGetContext(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ChatSubscription{
+		poller:   newPoller(ctx),
+		c:        c,
+		channel:  channel,
+		messages: make(chan ChatMessage, 64),
+	}
+	s.diff(messages)
+	s.start(interval, s.poll, func() { close(s.messages) })
+	return s, nil
+}
+
+// Messages returns the new messages in the order the server lists them.
+// The channel is closed when the subscription stops.
+func (s *ChatSubscription) Messages() <-chan ChatMessage {
+	return s.messages
+}
+
+func (s *ChatSubscription) poll(ctx context.Context) error {
+	messages, err := s.c.ChatGetContext(ctx, s.channel)
+	if err != nil {
+		return err
+	}
+	for _, message := range s.diff(messages) {
+		select {
+		case s.messages <- message:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+// diff returns the messages that weren't in the last poll and remembers
+// this one. Identical messages are told apart by how often they appear.
+func (s *ChatSubscription) diff(messages []ChatMessage) []ChatMessage {
+	seen := make(map[ChatMessage]int, len(messages))
+	var added []ChatMessage
+	for _, message := range messages {
+		seen[message]++
+		if seen[message] > s.seen[message] {
+			added = append(added, message)
+		}
+	}
+	s.seen = seen
+	return added
+}
@@ -0,0 +1,126 @@
+package EpicAuth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	EpicAuth "EpicAuth/EpicAuth"
+	"EpicAuth/EpicAuth/epicauthtest"
+)
+
+func newChatServer(t *testing.T, app epicauthtest.App, mutedUntil time.Time) *epicauthtest.Server {
+	t.Helper()
+	s := epicauthtest.NewServer(app)
+	t.Cleanup(s.Close)
+	s.AddUser(epicauthtest.User{
+		Username:      "alice",
+		Password:      "hunter2",
+		Subscriptions: []epicauthtest.Subscription{{Name: "default", Key: "K", Expiry: time.Now().Add(time.Hour)}},
+		MutedUntil:    mutedUntil,
+	})
+	return s
+}
+
+func chatSends(s *epicauthtest.Server) (n int) {
+	for _, form := range s.Requests() {
+		if form.Get("type") == "chatsend" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestChatSendThrottle(t *testing.T) {
+	t.Run("client", func(t *testing.T) {
+		s := newChatServer(t, epicauthtest.App{}, time.Time{})
+		c := loggedIn(t, s, EpicAuth.WithChatInterval(time.Hour))
+
+		if err := c.ChatSend("one", "general"); err != nil {
+			t.Fatalf("ChatSend() error = %v", err)
+		}
+		err := c.ChatSend("two", "general")
+		var chatErr *EpicAuth.ChatError
+		if !errors.As(err, &chatErr) || !errors.Is(err, EpicAuth.ErrRateLimited) || chatErr.RetryAfter <= 59*time.Minute {
+			t.Errorf("second ChatSend() error = %v, want %v with an hour to wait", err, EpicAuth.ErrRateLimited)
+		}
+		if err := c.ChatSend("three", "other"); err != nil {
+			t.Errorf("ChatSend() to another channel error = %v", err)
+		}
+		if n := chatSends(s); n != 2 {
+			t.Errorf("server received %d messages, want 2", n)
+		}
+	})
+
+	t.Run("server", func(t *testing.T) {
+		s := newChatServer(t, epicauthtest.App{ChatDelay: time.Hour}, time.Time{})
+		c := loggedIn(t, s, EpicAuth.WithChatInterval(0))
+
+		c.ChatSend("one", "general")
+		err := c.ChatSend("two", "general")
+		var chatErr *EpicAuth.ChatError
+		var apiErr *EpicAuth.APIError
+		if !errors.As(err, &chatErr) || !errors.As(err, &apiErr) || !errors.Is(err, EpicAuth.ErrRateLimited) || chatErr.RetryAfter <= 0 {
+			t.Errorf("ChatSend() refused by the server error = %v", err)
+		}
+		if err := c.ChatSend("three", "general"); !errors.Is(err, EpicAuth.ErrRateLimited) || errors.As(err, &apiErr) {
+			t.Errorf("ChatSend() right after error = %v, want it held back by the client", err)
+		}
+		if n := chatSends(s); n != 2 {
+			t.Errorf("server received %d messages, want 2", n)
+		}
+	})
+
+	t.Run("muted", func(t *testing.T) {
+		s := newChatServer(t, epicauthtest.App{}, time.Now().Add(time.Hour))
+		c := loggedIn(t, s)
+
+		err := c.ChatSend("hello", "general")
+		var chatErr *EpicAuth.ChatError
+		if !errors.As(err, &chatErr) || !errors.Is(err, EpicAuth.ErrMuted) || chatErr.Channel != "general" {
+			t.Errorf("ChatSend() while muted error = %v, want %v", err, EpicAuth.ErrMuted)
+		}
+	})
+}
+
+func TestSubscribeChat(t *testing.T) {
+	s := newChatServer(t, epicauthtest.App{}, time.Time{})
+	s.PostChat("general", epicauthtest.ChatMessage{Author: "bob", Message: "old", Timestamp: 1700000000})
+	c := loggedIn(t, s)
+
+	sub, err := c.SubscribeChat(context.Background(), "general", 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("SubscribeChat() error = %v", err)
+	}
+	defer sub.Stop()
+
+	s.PostChat("general", epicauthtest.ChatMessage{Author: "bob", Message: "hi", Timestamp: 1700000060})
+	s.PostChat("general", epicauthtest.ChatMessage{Author: "bob", Message: "hi", Timestamp: 1700000060})
+	s.PostChat("other", epicauthtest.ChatMessage{Author: "bob", Message: "elsewhere", Timestamp: 1700000060})
+	for i := 0; i < 2; i++ {
+		select {
+		case message := <-sub.Messages():
+			if when, err := message.Time(); message.Message != "hi" || err != nil || when.Unix() != 1700000060 {
+				t.Errorf("message %d = %+v, time %v, %v", i+1, message, when, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d of 2 new messages", i)
+		}
+	}
+	select {
+	case message := <-sub.Messages():
+		t.Errorf("unexpected message %+v", message)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.ExpireSessions()
+	select {
+	case <-sub.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription still running after the session expired")
+	}
+	if err := sub.Err(); !errors.Is(err, EpicAuth.ErrSessionExpired) {
+		t.Errorf("Err() = %v, want %v", err, EpicAuth.ErrSessionExpired)
+	}
+}
@@ -0,0 +1,432 @@
+package EpicAuth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Client talks to a single EpicAuth application and carries its own
+// session, user data and settings. Use NewClient to build one.
+type Client struct {
+	name     string
+	ownerID  string
+	version  string
+	token    TokenSource
+	apiURL   string
+	keys     KeySet
+	logger   Logger
+	redactor *Redactor
+
+	httpClient *http.Client
+	transport  Transport
+	retry      RetryPolicy
+	offline    OfflineConfig
+	files      *fileCache
+	vars       *varCache
+	chat       chatThrottle
+	hwid       HWIDProvider
+	now        func() time.Time
+	timeSource TimeSource
+
+	skewTolerance  time.Duration
+	maxClockOffset time.Duration
+	requireNonce   bool
+
+	mu          sync.RWMutex
+	sessionID   string
+	initialized bool
+	user        UserInfo
+	app         AppInfo
+	heartbeat   *Heartbeat
+	offlineMode bool
+	clock       *serverClock
+	tokenHash   string
+	userVars    *UserVars
+
+	// offlineLogin is the request of the login served from the cache
+	// while offline, sent again once the server is back.
+	offlineLogin map[string]string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithTokenPath enables the token system, reading the token from path.
+// "null" and "" leave it disabled.
+func WithTokenPath(path string) Option {
+	if path == "" || path == "null" {
+		return WithTokenSource(nil)
+	}
+	return WithTokenSource(FileToken(path))
+}
+
+// WithTokenSource enables the token system, reading the token from ts. A
+// nil ts disables it.
+func WithTokenSource(ts TokenSource) Option {
+	return func(c *Client) {
+		c.token = ts
+	}
+}
+
+// WithAPIURL overrides the API endpoint, e.g. for a custom domain.
+func WithAPIURL(apiURL string) Option {
+	return func(c *Client) {
+		c.apiURL = apiURL
+	}
+}
+
+// WithPublicKey trusts only the hex encoded ed25519 key publicKey.
+func WithPublicKey(publicKey string) Option {
+	return func(c *Client) {
+		c.keys = publicKeySet("default", publicKey)
+	}
+}
+
+// WithTrustedKeys trusts keys, tried in order. Listing the current and
+// the next key lets the server rotate keys without a client update.
+func WithTrustedKeys(keys ...TrustedKey) Option {
+	return func(c *Client) {
+		c.keys = append(KeySet(nil), keys...)
+	}
+}
+
+// WithEnvironment trusts the keys pinned for environment with PinKeys.
+func WithEnvironment(environment string) Option {
+	return func(c *Client) {
+		c.keys = PinnedKeys(environment)
+	}
+}
+
+// WithHTTPClient sends requests with hc instead of the default client,
+// e.g. to configure a proxy or custom TLS roots.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithTransport replaces the HTTP transport entirely. WithAPIURL and
+// WithHTTPClient have no effect when it is used.
+func WithTransport(t Transport) Option {
+	return func(c *Client) {
+		c.transport = t
+	}
+}
+
+// WithRetryPolicy sets how transient failures are retried. A zero
+// RetryPolicy disables retries.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithClockSkewTolerance sets how far a response's timestamp may be from
+// the expected server time.
+func WithClockSkewTolerance(tolerance time.Duration) Option {
+	return func(c *Client) {
+		c.skewTolerance = tolerance
+	}
+}
+
+// WithTimeSource checks the init response against ts instead of trusting
+// its timestamp. Before Init, ts is also used in place of the local clock.
+func WithTimeSource(ts TimeSource) Option {
+	return func(c *Client) {
+		c.timeSource = ts
+	}
+}
+
+// WithMaxClockOffset sets how far the init response's timestamp may be
+// from the local clock when the response doesn't echo the request's
+// nonce, and so could be an old one replayed.
+func WithMaxClockOffset(offset time.Duration) Option {
+	return func(c *Client) {
+		c.maxClockOffset = offset
+	}
+}
+
+// WithRequireNonce sets whether responses must echo the nonce sent with
+// the request. Either way, a nonce that is echoed has to match; requiring
+// it also rejects responses from servers that don't echo one.
+func WithRequireNonce(require bool) Option {
+	return func(c *Client) {
+		c.requireNonce = require
+	}
+}
+
+// WithHWIDProvider sets how the machine is identified, e.g. with a
+// CompositeHWID.
+func WithHWIDProvider(p HWIDProvider) Option {
+	return func(c *Client) {
+		c.hwid = p
+	}
+}
+
+// WithOfflineCache keeps the last successful login on disk so that Init,
+// Login and License keep working for a while when the server can't be
+// reached.
+func WithOfflineCache(cfg OfflineConfig) Option {
+	return func(c *Client) {
+		c.offline = cfg
+	}
+}
+
+// WithFileCache keeps downloaded files encrypted on disk, so that
+// downloading the same file again is served from the cache.
+func WithFileCache(cfg FileCacheConfig) Option {
+	return func(c *Client) {
+		if cfg.MaxSize == 0 {
+			cfg.MaxSize = DefaultFileCacheSize
+		}
+		if cfg.MaxAge == 0 {
+			cfg.MaxAge = DefaultFileCacheMaxAge
+		}
+		c.files = &fileCache{cfg: cfg}
+	}
+}
+
+// WithVarCache caches application variables in memory, so Var only goes
+// to the server once the cached value expires.
+func WithVarCache(cfg VarCacheConfig) Option {
+	return func(c *Client) {
+		if cfg.TTL == 0 {
+			cfg.TTL = DefaultVarCacheTTL
+		}
+		c.vars = &varCache{cfg: cfg, entries: make(map[string]varEntry)}
+	}
+}
+
+// WithChatInterval sets the least time between two ChatSend calls to one
+// channel. Zero only holds messages back after the server has refused
+// one for being too fast.
+func WithChatInterval(interval time.Duration) Option {
+	return func(c *Client) {
+		c.chat.interval = interval
+	}
+}
+
+// WithLogger sends an event for every request to l. A nil l disables
+// logging, including the default log file.
+func WithLogger(l Logger) Option {
+	return func(c *Client) {
+		c.logger = l
+	}
+}
+
+// WithRedactor sets what is redacted from logged requests and responses.
+// A nil r logs everything as is.
+func WithRedactor(r *Redactor) Option {
+	return func(c *Client) {
+		c.redactor = r
+	}
+}
+
+// WithDebugDir logs to log.txt in dir. An empty dir disables logging.
+//
+// Deprecated: use WithLogger with a FileLogger, or WithLogger(nil).
+func WithDebugDir(dir string) Option {
+	if dir == "" {
+		return WithLogger(nil)
+	}
+	return WithLogger(NewFileLogger(filepath.Join(dir, "log.txt")))
+}
+
+// NewClient returns a Client for the given application. Call Init before
+// using any other method.
+func NewClient(name, ownerID, version string, opts ...Option) *Client {
+	c := &Client{
+		name:     name,
+		ownerID:  ownerID,
+		version:  version,
+		apiURL:   APIUrl,
+		keys:     PinnedKeys(DefaultEnvironment),
+		redactor: DefaultRedactor(),
+		hwid:     DefaultHWIDProvider(),
+		now:      time.Now,
+		chat:     chatThrottle{interval: DefaultChatInterval},
+		retry:    DefaultRetryPolicy,
+
+		skewTolerance:  DefaultClockSkewTolerance,
+		maxClockOffset: DefaultMaxClockOffset,
+	}
+	if path, err := DefaultLogPath(); err == nil {
+		c.logger = NewFileLogger(path)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.transport == nil {
+		c.transport = &HTTPTransport{URL: c.apiURL, Client: c.httpClient}
+	}
+	return c
+}
+
+func (c *Client) Name() string    { return c.name }
+func (c *Client) OwnerID() string { return c.ownerID }
+func (c *Client) Version() string { return c.version }
+
+func (c *Client) SessionID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sessionID
+}
+
+func (c *Client) Initialized() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.initialized
+}
+
+// User returns the data of the logged in user.
+func (c *Client) User() UserInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.user
+}
+
+// HWID identifies this machine using the client's HWIDProvider.
+func (c *Client) HWID() (string, error) {
+	hwid, err := c.hwid.HWID()
+	if err != nil {
+		return "", fmt.Errorf("EpicAuth: reading hwid: %w", err)
+	}
+	return hwid, nil
+}
+
+// AppInfo returns the data loaded by the last FetchStats call.
+func (c *Client) AppInfo() AppInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.app
+}
+
+func (c *Client) CheckInit() error {
+	if !c.Initialized() {
+		return ErrNotInitialized
+	}
+	return nil
+}
+
+// session returns the form fields every authenticated request carries.
+func (c *Client) session(postData map[string]string) map[string]string {
+	postData["sessionid"] = c.SessionID()
+	postData["name"] = c.name
+	postData["ownerid"] = c.ownerID
+	return postData
+}
+
+// doRequest sends postData and returns the response once its signature
+// and timestamp check out.
+func (c *Client) doRequest(ctx context.Context, postData map[string]string) (*RawResponse, error) {
+	if c.Offline() && postData["type"] != "init" {
+		// Every call is a chance to get back online.
+		if err := c.reconnect(ctx); err != nil {
+			return nil, err
+		}
+		if _, ok := postData["sessionid"]; ok {
+			postData["sessionid"] = c.SessionID()
+		}
+	}
+
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+	requestBody := url.Values{}
+	for key, value := range postData {
+		requestBody.Set(key, value)
+	}
+	requestBody.Set("nonce", nonce)
+
+	start := time.Now()
+	response, err := c.retry.do(ctx, postData["type"], func(ctx context.Context) (*RawResponse, error) {
+		return c.transport.Send(ctx, requestBody)
+	})
+	event := RequestEvent{Type: postData["type"], Time: start, Err: err}
+	if err == nil {
+		event.StatusCode = response.StatusCode
+		event.KeyID, event.Signature, event.Err = c.verify(postData["type"], nonce, response)
+	}
+	if c.logger != nil {
+		event.Latency = time.Since(start)
+		if err == nil {
+			event.Response = c.redactor.redactResponse(postData["type"], response.Body)
+		}
+		event.Request = c.redactor.redactRequest(postData)
+		event.Request["nonce"] = nonce
+		c.logger.LogRequest(event)
+	}
+
+	if event.Err != nil {
+		return nil, event.Err
+	}
+	return response, nil
+}
+
+// verify checks the signature, timestamp and nonce of a requestType
+// response and returns the ID of the key that signed it.
+func (c *Client) verify(requestType, nonce string, response *RawResponse) (string, SignatureResult, error) {
+	signature := response.Signature
+	timestamp := response.Timestamp
+	if signature == "" || timestamp == "" {
+		return "", SignatureMissing, fmt.Errorf("%w: missing signature or timestamp in response headers", ErrSignatureInvalid)
+	}
+
+	serverTime, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", SignatureInvalid, fmt.Errorf("%w: invalid timestamp format: %v", ErrSignatureInvalid, err)
+	}
+
+	keyID, ok := c.keys.Verify(response.Body, signature, timestamp)
+	if !ok {
+		return "", SignatureInvalid, ErrSignatureInvalid
+	}
+	echoed, err := c.checkNonce(nonce, response.Body)
+	if err != nil {
+		return keyID, SignatureNonceMismatch, err
+	}
+	if err := c.checkTime(requestType, serverTime, echoed); err != nil {
+		return keyID, SignatureClockSkew, err
+	}
+	return keyID, SignatureValid, nil
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("EpicAuth: generating nonce: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// checkNonce checks that a signed body echoes nonce, which ties it to the
+// request it answers, and reports whether it did. Bodies that aren't JSON
+// objects, such as "EpicAuth_Invalid", can't carry a nonce and are never
+// a success anyway.
+func (c *Client) checkNonce(nonce string, body []byte) (echoed bool, err error) {
+	var echo struct {
+		Nonce *string `json:"nonce"`
+	}
+	if json.Unmarshal(body, &echo) != nil {
+		return false, nil
+	}
+	switch {
+	case echo.Nonce == nil && !c.requireNonce:
+		return false, nil
+	case echo.Nonce == nil:
+		return false, fmt.Errorf("%w: no nonce in response", ErrNonceMismatch)
+	case *echo.Nonce != nonce:
+		return false, ErrNonceMismatch
+	}
+	return true, nil
+}
@@ -0,0 +1,495 @@
+package EpicAuth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	EpicAuth "EpicAuth/EpicAuth"
+	"EpicAuth/EpicAuth/epicauthtest"
+)
+
+func newServer(t *testing.T) *epicauthtest.Server {
+	t.Helper()
+	s := epicauthtest.NewServer(epicauthtest.App{Download: "https://example.com/latest"})
+	t.Cleanup(s.Close)
+
+	s.AddLicense(epicauthtest.License{Key: "LICENSE-1", Subscription: "default", Duration: 24 * time.Hour})
+	s.AddLicense(epicauthtest.License{Key: "LICENSE-2", Subscription: "premium", Duration: 24 * time.Hour})
+	s.AddUser(epicauthtest.User{
+		Username:      "alice",
+		Password:      "hunter2",
+		Email:         "alice@example.com",
+		Subscriptions: []epicauthtest.Subscription{{Name: "default", Key: "K", Expiry: time.Now().Add(time.Hour)}},
+	})
+	return s
+}
+
+func newClient(t *testing.T, s *epicauthtest.Server, opts ...EpicAuth.Option) *EpicAuth.Client {
+	t.Helper()
+	opts = append([]EpicAuth.Option{
+		EpicAuth.WithAPIURL(s.URL),
+		EpicAuth.WithPublicKey(s.PublicKey),
+		EpicAuth.WithLogger(nil),
+		EpicAuth.WithHWIDProvider(EpicAuth.HWIDFunc(func() (string, error) { return "test-hwid", nil })),
+	}, opts...)
+	return EpicAuth.NewClient(epicauthtest.DefaultName, epicauthtest.DefaultOwnerID, epicauthtest.DefaultVersion, opts...)
+}
+
+func initClient(t *testing.T, s *epicauthtest.Server, opts ...EpicAuth.Option) *EpicAuth.Client {
+	t.Helper()
+	c := newClient(t, s, opts...)
+	if err := c.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	return c
+}
+
+func loggedIn(t *testing.T, s *epicauthtest.Server, opts ...EpicAuth.Option) *EpicAuth.Client {
+	t.Helper()
+	c := initClient(t, s, opts...)
+	if _, err := c.Login("alice", "hunter2"); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	return c
+}
+
+func TestInit(t *testing.T) {
+	s := newServer(t)
+
+	t.Run("ok", func(t *testing.T) {
+		c := initClient(t, s)
+		if !c.Initialized() || c.SessionID() == "" {
+			t.Errorf("client not initialized: %v %q", c.Initialized(), c.SessionID())
+		}
+		if err := c.Init(); !errors.Is(err, EpicAuth.ErrAlreadyInitialized) {
+			t.Errorf("second Init() error = %v, want %v", err, EpicAuth.ErrAlreadyInitialized)
+		}
+	})
+
+	t.Run("bad owner id", func(t *testing.T) {
+		c := EpicAuth.NewClient("test", "short", "1.0", EpicAuth.WithAPIURL(s.URL), EpicAuth.WithLogger(nil))
+		if err := c.Init(); !errors.Is(err, EpicAuth.ErrInvalidApp) {
+			t.Errorf("Init() error = %v, want %v", err, EpicAuth.ErrInvalidApp)
+		}
+	})
+
+	t.Run("unknown app", func(t *testing.T) {
+		c := EpicAuth.NewClient("other", epicauthtest.DefaultOwnerID, "1.0",
+			EpicAuth.WithAPIURL(s.URL), EpicAuth.WithPublicKey(s.PublicKey), EpicAuth.WithLogger(nil))
+		if err := c.Init(); !errors.Is(err, EpicAuth.ErrInvalidApp) {
+			t.Errorf("Init() error = %v, want %v", err, EpicAuth.ErrInvalidApp)
+		}
+	})
+
+	t.Run("invalid version", func(t *testing.T) {
+		c := EpicAuth.NewClient(epicauthtest.DefaultName, epicauthtest.DefaultOwnerID, "0.9",
+			EpicAuth.WithAPIURL(s.URL), EpicAuth.WithPublicKey(s.PublicKey), EpicAuth.WithLogger(nil))
+		err := c.Init()
+		var apiErr *EpicAuth.APIError
+		if !errors.As(err, &apiErr) || !errors.Is(err, EpicAuth.ErrInvalidVersion) {
+			t.Fatalf("Init() error = %v, want %v", err, EpicAuth.ErrInvalidVersion)
+		}
+		if apiErr.Download != "https://example.com/latest" {
+			t.Errorf("Download = %q", apiErr.Download)
+		}
+	})
+
+	t.Run("wrong public key", func(t *testing.T) {
+		c := newClient(t, s, EpicAuth.WithPublicKey(EpicAuth.PublicKey))
+		if err := c.Init(); !errors.Is(err, EpicAuth.ErrSignatureInvalid) {
+			t.Errorf("Init() error = %v, want %v", err, EpicAuth.ErrSignatureInvalid)
+		}
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := newClient(t, s).InitContext(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("InitContext() error = %v, want %v", err, context.Canceled)
+		}
+	})
+}
+
+func TestServerClockSkew(t *testing.T) {
+	s := newServer(t)
+	s.SetClock(func() time.Time { return time.Now().Add(30 * time.Minute) })
+
+	t.Run("tracked from init", func(t *testing.T) {
+		c := loggedIn(t, s)
+		if offset, ok := c.ClockOffset(); !ok || offset < 29*time.Minute || offset > 31*time.Minute {
+			t.Errorf("ClockOffset() = %v, %v, want 30m", offset, ok)
+		}
+		if _, err := c.Check(); err != nil {
+			t.Errorf("Check() error = %v", err)
+		}
+
+		// Responses that disagree with the tracked clock, such as replayed
+		// ones, are still rejected.
+		s.SetClock(func() time.Time { return time.Now().Add(31 * time.Minute) })
+		defer s.SetClock(func() time.Time { return time.Now().Add(30 * time.Minute) })
+		if _, err := c.Check(); !errors.Is(err, EpicAuth.ErrClockSkew) {
+			t.Errorf("Check() error = %v, want %v", err, EpicAuth.ErrClockSkew)
+		}
+		if _, err := loggedIn(t, s, EpicAuth.WithClockSkewTolerance(2*time.Minute)).Check(); err != nil {
+			t.Errorf("Check() with a larger tolerance error = %v", err)
+		}
+	})
+
+	t.Run("time source", func(t *testing.T) {
+		local := EpicAuth.TimeSourceFunc(func() (time.Time, error) { return time.Now(), nil })
+		if err := newClient(t, s, EpicAuth.WithTimeSource(local)).Init(); !errors.Is(err, EpicAuth.ErrClockSkew) {
+			t.Errorf("Init() error = %v, want %v", err, EpicAuth.ErrClockSkew)
+		}
+
+		trusted := EpicAuth.TimeSourceFunc(func() (time.Time, error) { return time.Now().Add(30 * time.Minute), nil })
+		if err := newClient(t, s, EpicAuth.WithTimeSource(trusted)).Init(); err != nil {
+			t.Errorf("Init() error = %v", err)
+		}
+	})
+}
+
+func TestServerWithoutNonce(t *testing.T) {
+	s := epicauthtest.NewServer(epicauthtest.App{NoNonce: true})
+	defer s.Close()
+
+	if err := newClient(t, s).Init(); err != nil {
+		t.Errorf("Init() error = %v", err)
+	}
+	if err := newClient(t, s, EpicAuth.WithRequireNonce(true)).Init(); !errors.Is(err, EpicAuth.ErrNonceMismatch) {
+		t.Errorf("Init() with WithRequireNonce(true) error = %v, want %v", err, EpicAuth.ErrNonceMismatch)
+	}
+
+	// Without a nonce an init response may be an old one replayed, so
+	// only a small clock offset is accepted.
+	s.SetClock(func() time.Time { return time.Now().Add(-24 * time.Hour) })
+	if err := newClient(t, s).Init(); !errors.Is(err, EpicAuth.ErrClockSkew) {
+		t.Errorf("Init() with server clock a day behind error = %v, want %v", err, EpicAuth.ErrClockSkew)
+	}
+	if err := newClient(t, s, EpicAuth.WithMaxClockOffset(25*time.Hour)).Init(); err != nil {
+		t.Errorf("Init() with WithMaxClockOffset(25h) error = %v", err)
+	}
+	s.SetClock(func() time.Time { return time.Now().Add(5 * time.Minute) })
+	if err := newClient(t, s).Init(); !errors.Is(err, EpicAuth.ErrClockSkew) {
+		t.Errorf("Init() with server clock 5m ahead error = %v, want %v", err, EpicAuth.ErrClockSkew)
+	}
+	s.SetClock(func() time.Time { return time.Now().Add(10 * time.Second) })
+	if err := newClient(t, s).Init(); err != nil {
+		t.Errorf("Init() with server clock 10s ahead error = %v", err)
+	}
+}
+
+func TestNotInitialized(t *testing.T) {
+	c := newClient(t, newServer(t))
+	if _, err := c.Login("alice", "hunter2"); !errors.Is(err, EpicAuth.ErrNotInitialized) {
+		t.Errorf("Login() error = %v, want %v", err, EpicAuth.ErrNotInitialized)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	s := newServer(t)
+
+	c := loggedIn(t, s)
+	user := c.User()
+	if user.Username != "alice" || user.IP != "127.0.0.1" || user.HWID == "" {
+		t.Errorf("User() = %+v", user)
+	}
+	if len(user.Subscriptions) != 1 || user.Subscriptions[0].Subscription != "default" {
+		t.Errorf("Subscriptions = %+v", user.Subscriptions)
+	}
+
+	if _, err := initClient(t, s).Login("alice", "wrong"); err == nil {
+		t.Error("Login() with wrong password succeeded")
+	}
+}
+
+func TestLoginFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    epicauthtest.User
+		wantErr error
+	}{
+		{"hwid mismatch", epicauthtest.User{HWID: "another-machine"}, EpicAuth.ErrHWIDMismatch},
+		{"banned", epicauthtest.User{Banned: true}, EpicAuth.ErrBanned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			tt.user.Username, tt.user.Password = "bob", "pw"
+			tt.user.Subscriptions = []epicauthtest.Subscription{{Name: "default", Expiry: time.Now().Add(time.Hour)}}
+			s.AddUser(tt.user)
+
+			_, err := initClient(t, s).Login("bob", "pw")
+			var apiErr *EpicAuth.APIError
+			if !errors.As(err, &apiErr) || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login() error = %v, want %v", err, tt.wantErr)
+			}
+			if apiErr.Type != "login" || apiErr.Message == "" {
+				t.Errorf("APIError = %+v", apiErr)
+			}
+		})
+	}
+}
+
+func TestRegisterUpgradeLicense(t *testing.T) {
+	s := newServer(t)
+
+	c := initClient(t, s)
+	response, err := c.Register("carol", "pw", "LICENSE-1")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if response.Info.Username != "carol" {
+		t.Errorf("Register() info = %+v", response.Info)
+	}
+
+	if _, err := c.Upgrade("carol", "LICENSE-2"); err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	if user, _ := s.User("carol"); len(user.Subscriptions) != 2 {
+		t.Errorf("subscriptions after upgrade = %+v", user.Subscriptions)
+	}
+
+	if _, err := initClient(t, s).License("LICENSE-1"); err == nil {
+		t.Error("License() with used key succeeded")
+	}
+
+	s.AddLicense(epicauthtest.License{Key: "LICENSE-3", Subscription: "default", Duration: time.Hour})
+	if _, err := initClient(t, s).License("LICENSE-3"); err != nil {
+		t.Errorf("License() error = %v", err)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	s := newServer(t)
+	s.SetVar("motd", "hello")
+	c := loggedIn(t, s)
+
+	if got, err := c.Var("motd"); err != nil || got != "hello" {
+		t.Errorf("Var() = %q, %v", got, err)
+	}
+	if _, err := c.Var("missing"); !errors.Is(err, EpicAuth.ErrVarNotFound) {
+		t.Errorf("Var() of missing variable error = %v, want %v", err, EpicAuth.ErrVarNotFound)
+	}
+
+	if _, err := c.GetVar("theme"); !errors.Is(err, EpicAuth.ErrVarNotFound) {
+		t.Errorf("GetVar() of unset variable error = %v, want %v", err, EpicAuth.ErrVarNotFound)
+	}
+	if err := c.SetVar("theme", "dark"); err != nil {
+		t.Fatalf("SetVar() error = %v", err)
+	}
+	if got, err := c.GetVar("theme"); err != nil || got != "dark" {
+		t.Errorf("GetVar() = %q, %v", got, err)
+	}
+}
+
+func TestUserVars(t *testing.T) {
+	s := newServer(t)
+	s.AddUser(epicauthtest.User{
+		Username:      "bob",
+		Password:      "pw",
+		Subscriptions: []epicauthtest.Subscription{{Name: "default", Key: "K", Expiry: time.Now().Add(time.Hour)}},
+		Vars:          map[string]string{"volume": "7", "muted": "nope"},
+	})
+	c := loggedIn(t, s)
+	vars := c.UserVars()
+	ctx := context.Background()
+
+	if _, ok, err := vars.String(ctx, "theme"); ok || err != nil {
+		t.Errorf("String() of unset variable = %v, %v, want not found", ok, err)
+	}
+
+	type layout struct {
+		Columns int
+		Panels  []string
+	}
+	vars.SetString("theme", "dark")
+	vars.SetInt("volume", 11)
+	vars.SetBool("muted", true)
+	if err := vars.SetJSON("layout", layout{Columns: 2, Panels: []string{"chat", "news"}}); err != nil {
+		t.Fatalf("SetJSON() error = %v", err)
+	}
+	if got, ok, err := vars.String(ctx, "theme"); got != "dark" || !ok || err != nil {
+		t.Errorf("String() before Flush = %q, %v, %v", got, ok, err)
+	}
+	if user, _ := s.User("alice"); len(user.Vars) != 0 {
+		t.Errorf("server has %v before Flush", user.Vars)
+	}
+	if got := vars.Pending(); len(got) != 4 {
+		t.Errorf("Pending() = %v", got)
+	}
+
+	if err := vars.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	user, _ := s.User("alice")
+	if user.Vars["theme"] != "dark" || user.Vars["volume"] != "11" || user.Vars["muted"] != "true" ||
+		user.Vars["layout"] != `{"Columns":2,"Panels":["chat","news"]}` {
+		t.Errorf("server has %v after Flush", user.Vars)
+	}
+	if got := vars.Pending(); len(got) != 0 {
+		t.Errorf("Pending() after Flush = %v", got)
+	}
+
+	// Logging in as bob drops alice's cache.
+	if _, err := c.Login("bob", "pw"); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if got, ok, err := vars.Int(ctx, "volume"); got != 7 || !ok || err != nil {
+		t.Errorf("Int() = %d, %v, %v", got, ok, err)
+	}
+	if _, ok, err := vars.Bool(ctx, "muted"); !ok || err == nil {
+		t.Errorf("Bool() of a non-boolean = %v, %v, want an error", ok, err)
+	}
+	var got layout
+	if ok, err := vars.JSON(ctx, "layout", &got); ok || err != nil {
+		t.Errorf("JSON() of bob's unset variable = %v, %v", ok, err)
+	}
+
+	s.ExpireSessions()
+	vars.SetString("theme", "light")
+	if err := vars.Flush(ctx); !errors.Is(err, EpicAuth.ErrSessionExpired) {
+		t.Errorf("Flush() with an expired session error = %v, want %v", err, EpicAuth.ErrSessionExpired)
+	}
+	if got := vars.Pending(); len(got) != 1 {
+		t.Errorf("Pending() after a failed Flush = %v, want theme", got)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	s := newServer(t)
+	s.AddFile("123456", []byte("file contents"))
+	c := loggedIn(t, s)
+
+	got, err := c.Download("123456")
+	if err != nil || string(got) != "file contents" {
+		t.Errorf("Download() = %q, %v", got, err)
+	}
+	if _, err := c.Download("999999"); err == nil {
+		t.Error("Download() of missing file succeeded")
+	}
+}
+
+func TestChatAndOnline(t *testing.T) {
+	s := newServer(t)
+	c := loggedIn(t, s)
+
+	if err := c.ChatSend("hi there", "general"); err != nil {
+		t.Fatalf("ChatSend() error = %v", err)
+	}
+	messages, err := c.ChatGet("general")
+	if err != nil || len(messages) != 1 || messages[0].Author != "alice" || messages[0].Message != "hi there" {
+		t.Errorf("ChatGet() = %+v, %v", messages, err)
+	}
+
+	online, err := c.FetchOnline()
+	if err != nil || len(online) != 1 || online[0].Credential != "alice" {
+		t.Errorf("FetchOnline() = %+v, %v", online, err)
+	}
+
+	stats, err := c.FetchStats()
+	if err != nil || stats.NumUsers != 1 || stats.NumOnlineUsers != 1 || stats.NumKeys != 2 || stats.Version != "1.0" ||
+		stats.CustomerPanelURL == nil || stats.CustomerPanelURL.Host != "EpicAuth.cc" {
+		t.Errorf("FetchStats() = %+v, %v", stats, err)
+	}
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	s := newServer(t)
+	c := loggedIn(t, s)
+
+	if ok, err := c.Check(); !ok || err != nil {
+		t.Errorf("Check() = %v, %v", ok, err)
+	}
+	if err := c.ChangeUsername("alice2"); err != nil {
+		t.Errorf("ChangeUsername() error = %v", err)
+	}
+	if err := c.Logout(); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if ok, err := c.Check(); ok || !errors.Is(err, EpicAuth.ErrSessionExpired) {
+		t.Errorf("Check() after logout = %v, %v", ok, err)
+	}
+}
+
+func TestBanAndBlacklist(t *testing.T) {
+	s := newServer(t)
+	c := loggedIn(t, s)
+
+	if blacklisted, err := c.CheckBlack(); blacklisted || err != nil {
+		t.Errorf("CheckBlack() = %v, %v", blacklisted, err)
+	}
+	if err := c.Ban(); err != nil {
+		t.Fatalf("Ban() error = %v", err)
+	}
+	if blacklisted, err := c.CheckBlack(); !blacklisted || err != nil {
+		t.Errorf("CheckBlack() after ban = %v, %v", blacklisted, err)
+	}
+
+	s.ExpireSessions()
+	if _, err := c.CheckBlack(); !errors.Is(err, EpicAuth.ErrSessionExpired) {
+		t.Errorf("CheckBlack() with an expired session error = %v, want %v", err, EpicAuth.ErrSessionExpired)
+	}
+}
+
+func TestWebhookAndForgot(t *testing.T) {
+	s := newServer(t)
+	s.SetWebhook("7kR0UedlVI", `{"ok":true}`)
+	c := loggedIn(t, s)
+
+	if got, err := c.Webhook("7kR0UedlVI", "", "", ""); err != nil || got != `{"ok":true}` {
+		t.Errorf("Webhook() = %q, %v, want the webhook's response", got, err)
+	}
+	if _, err := c.Webhook("missing", "", "", ""); err == nil {
+		t.Error("Webhook() of missing webhook succeeded")
+	}
+	if _, err := c.Forgot("alice", "alice@example.com"); err != nil {
+		t.Errorf("Forgot() error = %v", err)
+	}
+}
+
+func TestClientsAreIndependent(t *testing.T) {
+	s := newServer(t)
+	s.AddUser(epicauthtest.User{
+		Username:      "bob",
+		Password:      "pw",
+		Subscriptions: []epicauthtest.Subscription{{Name: "default", Expiry: time.Now().Add(time.Hour)}},
+	})
+
+	alice := loggedIn(t, s)
+	bob := initClient(t, s)
+	if _, err := bob.Login("bob", "pw"); err != nil {
+		t.Fatal(err)
+	}
+
+	if alice.SessionID() == bob.SessionID() {
+		t.Error("clients share a session")
+	}
+	if alice.User().Username != "alice" || bob.User().Username != "bob" {
+		t.Errorf("users = %q, %q", alice.User().Username, bob.User().Username)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	s := newServer(t)
+	retired, err := EpicAuth.ParseTrustedKey("retired", EpicAuth.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := EpicAuth.ParseTrustedKey("current", s.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newClient(t, s, EpicAuth.WithTrustedKeys(retired, current))
+	if err := c.Init(); err != nil {
+		t.Errorf("Init() error = %v", err)
+	}
+
+	current.NotAfter = time.Now().Add(-time.Hour)
+	c = newClient(t, s, EpicAuth.WithTrustedKeys(retired, current))
+	if err := c.Init(); !errors.Is(err, EpicAuth.ErrSignatureInvalid) {
+		t.Errorf("Init() with expired key error = %v, want %v", err, EpicAuth.ErrSignatureInvalid)
+	}
+}
@@ -0,0 +1,107 @@
+package EpicAuth
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultClockSkewTolerance is how far a response's timestamp may be from
+// the expected server time unless WithClockSkewTolerance says otherwise.
+const DefaultClockSkewTolerance = 25 * time.Second
+
+// DefaultMaxClockOffset is how far the init response's timestamp may be
+// from the local clock, when nothing else vouches for it, unless
+// WithMaxClockOffset says otherwise. It is no wider than the skew
+// tolerance, so a replayed init response is no older than any other
+// response may be.
+const DefaultMaxClockOffset = DefaultClockSkewTolerance
+
+// TimeSource is a clock more trustworthy than the local one, e.g. an NTP
+// client.
+type TimeSource interface {
+	Now() (time.Time, error)
+}
+
+// TimeSourceFunc adapts a function to TimeSource.
+type TimeSourceFunc func() (time.Time, error)
+
+func (f TimeSourceFunc) Now() (time.Time, error) {
+	return f()
+}
+
+// serverClock estimates the server's time from the init response and
+// the local clock's progress since, which doesn't care how far off the
+// local wall clock is.
+type serverClock struct {
+	server time.Time
+	local  time.Time
+}
+
+// ClockOffset returns how far the server's clock is ahead of the local
+// one, as measured at Init. ok is false before Init.
+func (c *Client) ClockOffset() (offset time.Duration, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.clock == nil {
+		return 0, false
+	}
+	return c.clock.server.Sub(c.clock.local), true
+}
+
+// serverNow returns the best estimate of the server's time: the clock
+// tracked since Init, else the time source, else the local clock.
+func (c *Client) serverNow() time.Time {
+	c.mu.RLock()
+	clock := c.clock
+	c.mu.RUnlock()
+	if clock != nil {
+		return clock.server.Add(c.now().Sub(clock.local))
+	}
+	if c.timeSource != nil {
+		if now, err := c.timeSource.Now(); err == nil {
+			return now
+		}
+	}
+	return c.now()
+}
+
+// checkTime checks a verified response timestamp. Without a time source
+// the init response sets the server clock rather than being checked
+// against it; every later response has to agree with that clock, so old
+// responses can't be replayed. An init response that didn't echo the
+// request's nonce could itself be replayed, so it may only be
+// maxClockOffset from the local clock.
+func (c *Client) checkTime(requestType string, serverTime int64, echoed bool) error {
+	c.mu.RLock()
+	synced := c.clock != nil
+	c.mu.RUnlock()
+
+	var expected time.Time
+	tolerance := c.skewTolerance
+	switch {
+	case synced || requestType != "init":
+		expected = c.serverNow()
+	case c.timeSource != nil:
+		now, err := c.timeSource.Now()
+		if err != nil {
+			return fmt.Errorf("EpicAuth: reading time source: %w", err)
+		}
+		expected = now
+	case !echoed:
+		expected, tolerance = c.now(), c.maxClockOffset
+	}
+
+	if !expected.IsZero() {
+		skew := abs(expected.Unix() - serverTime)
+		if time.Duration(skew)*time.Second > tolerance {
+			return fmt.Errorf("%w: %d seconds, try syncing your date and time settings", ErrClockSkew, skew)
+		}
+	}
+
+	if requestType == "init" && !synced {
+		c.mu.Lock()
+		c.clock = &serverClock{server: time.Unix(serverTime, 0), local: c.now()}
+		c.mu.Unlock()
+	}
+	return nil
+}
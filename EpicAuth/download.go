@@ -0,0 +1,179 @@
+package EpicAuth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DownloadOptions configures DownloadTo and DownloadFile.
+type DownloadOptions struct {
+	// Progress, if set, is called as the file is written with the number
+	// of bytes written so far and the file's total size.
+	Progress func(written, total int64)
+	// SHA256 is the expected hex encoded SHA-256 of the file. Empty skips
+	// the check.
+	SHA256 string
+}
+
+// DownloadError is returned by DownloadTo and DownloadFile. Err is an
+// *APIError, ErrInvalidResponse, a *ChecksumError, the context's error
+// or the error from writing the file.
+type DownloadError struct {
+	FileID string
+	Err    error
+}
+
+func (e *DownloadError) Error() string {
+	return fmt.Sprintf("EpicAuth: downloading file %s: %v", e.FileID, e.Err)
+}
+
+func (e *DownloadError) Unwrap() error {
+	return e.Err
+}
+
+// ChecksumError means a downloaded file doesn't have the expected
+// SHA-256.
+type ChecksumError struct {
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch: got sha256 %s, want %s", e.Actual, e.Expected)
+}
+
+// DownloadTo downloads a file and writes it to w, decoding it as it goes
+// rather than holding a decoded copy in memory. The signed response
+// itself still has to be read in full to verify it.
+//
+// With WithFileCache, a cached copy is written instead if the session is
+// still valid and the copy has the expected SHA-256 or, when none is
+// given, is younger than FileCacheConfig.MaxAge.
+//
+// w may have received some or all of the file when the SHA-256 check
+// fails; use DownloadFile to only ever see complete, verified files.
+func (c *Client) DownloadTo(ctx context.Context, fileID string, w io.Writer, opts DownloadOptions) (int64, error) {
+	n, err := c.downloadTo(ctx, fileID, w, opts)
+	if err != nil {
+		return n, &DownloadError{FileID: fileID, Err: err}
+	}
+	return n, nil
+}
+
+func (c *Client) downloadTo(ctx context.Context, fileID string, w io.Writer, opts DownloadOptions) (int64, error) {
+	if err := c.CheckInit(); err != nil {
+		return 0, err
+	}
+	if c.files != nil {
+		if n, ok, err := c.downloadCached(ctx, fileID, w, opts); ok {
+			return n, err
+		}
+	}
+
+	var response FileResponse
+	if err := c.call(ctx, c.session(map[string]string{
+		"type":   "file",
+		"fileid": fileID,
+	}), &response); err != nil {
+		return 0, err
+	}
+
+	pw := &progressWriter{ctx: ctx, w: w, total: int64(len(response.Contents) / 2), progress: opts.Progress}
+	var digest hash.Hash
+	var contents *bytes.Buffer
+	if opts.SHA256 != "" {
+		digest = sha256.New()
+		pw.w = io.MultiWriter(w, digest)
+	}
+	if c.files != nil && pw.total <= c.files.cfg.MaxSize {
+		if digest == nil {
+			digest = sha256.New()
+		}
+		contents = bytes.NewBuffer(make([]byte, 0, pw.total))
+		pw.w = io.MultiWriter(w, digest, contents)
+	}
+
+	n, err := io.Copy(pw, hex.NewDecoder(strings.NewReader(response.Contents)))
+	if err != nil {
+		var hexErr hex.InvalidByteError
+		if errors.As(err, &hexErr) || err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("%w: decoding file contents: %v", ErrInvalidResponse, err)
+		}
+		return n, err
+	}
+
+	if digest != nil {
+		actual := hex.EncodeToString(digest.Sum(nil))
+		if opts.SHA256 != "" && !strings.EqualFold(actual, opts.SHA256) {
+			return n, &ChecksumError{Expected: strings.ToLower(opts.SHA256), Actual: actual}
+		}
+		if contents != nil {
+			// The cache only saves a later download, so failing to
+			// write it doesn't fail this one.
+			c.storeCachedFile(fileID, contents.Bytes(), actual)
+		}
+	}
+	return n, nil
+}
+
+// DownloadFile downloads a file to path. The file is written to a
+// temporary file next to path first and only renamed into place once it
+// is complete and its SHA-256, if given, matches.
+func (c *Client) DownloadFile(ctx context.Context, fileID, path string, opts DownloadOptions) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return &DownloadError{FileID: fileID, Err: err}
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := c.DownloadTo(ctx, fileID, tmp, opts); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return &DownloadError{FileID: fileID, Err: err}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return &DownloadError{FileID: fileID, Err: err}
+	}
+	if err := tmp.Close(); err != nil {
+		return &DownloadError{FileID: fileID, Err: err}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return &DownloadError{FileID: fileID, Err: err}
+	}
+	return nil
+}
+
+// progressWriter reports progress and stops the copy once ctx is done.
+type progressWriter struct {
+	ctx      context.Context
+	w        io.Writer
+	written  int64
+	total    int64
+	progress func(written, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	if err := p.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	if p.progress != nil && n > 0 {
+		p.progress(p.written, p.total)
+	}
+	return n, err
+}
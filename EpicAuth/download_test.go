@@ -0,0 +1,156 @@
+package EpicAuth_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	EpicAuth "EpicAuth/EpicAuth"
+)
+
+func TestDownloadTo(t *testing.T) {
+	s := newServer(t)
+	contents := bytes.Repeat([]byte("0123456789abcdef"), 16<<10)
+	sum := sha256.Sum256(contents)
+	s.AddFile("123456", contents)
+	c := loggedIn(t, s)
+
+	var buf bytes.Buffer
+	var calls int
+	var last, total int64
+	n, err := c.DownloadTo(context.Background(), "123456", &buf, EpicAuth.DownloadOptions{
+		Progress: func(written, size int64) { calls, last, total = calls+1, written, size },
+		SHA256:   hex.EncodeToString(sum[:]),
+	})
+	if err != nil || n != int64(len(contents)) || !bytes.Equal(buf.Bytes(), contents) {
+		t.Fatalf("DownloadTo() = %d, %v", n, err)
+	}
+	if calls < 2 || last != n || total != n {
+		t.Errorf("progress: %d calls, last %d of %d, want several up to %d", calls, last, total, n)
+	}
+
+	_, err = c.DownloadTo(context.Background(), "123456", &buf, EpicAuth.DownloadOptions{SHA256: "00"})
+	var checksumErr *EpicAuth.ChecksumError
+	var downloadErr *EpicAuth.DownloadError
+	if !errors.As(err, &checksumErr) || !errors.As(err, &downloadErr) || downloadErr.FileID != "123456" {
+		t.Errorf("DownloadTo() with a wrong checksum error = %v", err)
+	}
+
+	if _, err := c.DownloadTo(context.Background(), "999999", &buf, EpicAuth.DownloadOptions{}); !errors.Is(err, EpicAuth.ErrFileNotFound) {
+		t.Errorf("DownloadTo() of a missing file error = %v, want %v", err, EpicAuth.ErrFileNotFound)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = c.DownloadTo(ctx, "123456", &buf, EpicAuth.DownloadOptions{Progress: func(int64, int64) { cancel() }})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("DownloadTo() cancelled mid-way error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	s := newServer(t)
+	s.AddFile("123456", []byte("file contents"))
+	sum := sha256.Sum256([]byte("file contents"))
+	c := loggedIn(t, s)
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "ok.bin")
+	if err := c.DownloadFile(context.Background(), "123456", path, EpicAuth.DownloadOptions{SHA256: hex.EncodeToString(sum[:])}); err != nil {
+		t.Fatalf("DownloadFile() error = %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "file contents" {
+		t.Errorf("file contents = %q", got)
+	}
+
+	bad := filepath.Join(dir, "bad.bin")
+	var checksumErr *EpicAuth.ChecksumError
+	if err := c.DownloadFile(context.Background(), "123456", bad, EpicAuth.DownloadOptions{SHA256: "00"}); !errors.As(err, &checksumErr) {
+		t.Errorf("DownloadFile() with a wrong checksum error = %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %d files after a failed download, want only ok.bin", len(entries))
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	s := newServer(t)
+	s.AddFile("123456", []byte("version 1"))
+	s.AddFile("654321", bytes.Repeat([]byte("x"), 60))
+	var mu sync.Mutex
+	requests := map[string]int{}
+	logger := EpicAuth.LoggerFunc(func(event EpicAuth.RequestEvent) {
+		mu.Lock()
+		requests[event.Type]++
+		mu.Unlock()
+	})
+	dir := t.TempDir()
+	c := loggedIn(t, s, EpicAuth.WithLogger(logger), EpicAuth.WithFileCache(EpicAuth.FileCacheConfig{Dir: dir, MaxSize: 100}))
+
+	for i := 0; i < 2; i++ {
+		if got, err := c.Download("123456"); err != nil || string(got) != "version 1" {
+			t.Fatalf("Download() #%d = %q, %v", i+1, got, err)
+		}
+	}
+	if requests["file"] != 1 || requests["check"] != 1 {
+		t.Errorf("two downloads sent %d file and %d check requests, want 1 and 1", requests["file"], requests["check"])
+	}
+
+	sum := sha256.Sum256([]byte("version 2"))
+	s.AddFile("123456", []byte("version 2"))
+	if got, _ := c.Download("123456"); string(got) != "version 1" {
+		t.Errorf("Download() without a checksum = %q, want the cached copy", got)
+	}
+	var buf bytes.Buffer
+	if _, err := c.DownloadTo(context.Background(), "123456", &buf, EpicAuth.DownloadOptions{SHA256: hex.EncodeToString(sum[:])}); err != nil || buf.String() != "version 2" {
+		t.Errorf("DownloadTo() with a new checksum = %q, %v, want a fresh download", buf.String(), err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("cache holds %d files, want the old copy replaced", len(entries))
+	}
+	for _, entry := range entries {
+		if data, _ := os.ReadFile(filepath.Join(dir, entry.Name())); bytes.Contains(data, []byte("version")) {
+			t.Error("cached file is stored in plain text")
+		}
+	}
+
+	// Both files don't fit, so caching the second evicts the first.
+	if _, err := c.Download("654321"); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	files := requests["file"]
+	c.Download("123456")
+	if requests["file"] != files+1 {
+		t.Error("evicted file was served from the cache")
+	}
+
+	// Without a checksum, a copy older than MaxAge is downloaded again.
+	stale := loggedIn(t, s, EpicAuth.WithLogger(logger), EpicAuth.WithFileCache(EpicAuth.FileCacheConfig{Dir: dir, MaxSize: 100, MaxAge: time.Millisecond}))
+	stale.Download("123456")
+	time.Sleep(5 * time.Millisecond)
+	files = requests["file"]
+	if got, err := stale.Download("123456"); err != nil || string(got) != "version 2" || requests["file"] != files+1 {
+		t.Errorf("Download() of an expired copy = %q, %v after %d file requests, want a fresh download", got, err, requests["file"]-files)
+	}
+
+	if err := c.Logout(); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if _, err := c.Download("123456"); err == nil {
+		t.Error("Download() after Logout served the cached copy")
+	}
+
+	if err := c.ClearFileCache(); err != nil {
+		t.Fatalf("ClearFileCache() error = %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("cache directory still exists after ClearFileCache: %v", err)
+	}
+}
@@ -0,0 +1,555 @@
+// Package epicauthtest provides a local fake of the EpicAuth 1.3 API for
+// tests. Responses are signed with a throwaway ed25519 key using the same
+// x-signature-ed25519 / x-signature-timestamp scheme as the real server.
+package epicauthtest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// App describes the application the server pretends to host. Empty
+// fields get the defaults below.
+type App struct {
+	Name     string
+	OwnerID  string
+	Version  string
+	Download string // sent along with "invalidver"
+	Token    string // if set, init requires this token and its hash
+	// NoNonce makes the server behave like older versions that don't
+	// echo the request nonce.
+	NoNonce bool
+	// ChatDelay is how long a user has to wait between two messages to
+	// the same channel.
+	ChatDelay time.Duration
+}
+
+const (
+	DefaultName    = "test"
+	DefaultOwnerID = "abcdefghij"
+	DefaultVersion = "1.0"
+)
+
+type User struct {
+	Username      string
+	Password      string
+	Email         string
+	HWID          string // bound on first login if empty
+	Banned        bool
+	Subscriptions []Subscription
+	Vars          map[string]string
+	MutedUntil    time.Time // chat messages are refused until then
+}
+
+type Subscription struct {
+	Name   string
+	Key    string
+	Expiry time.Time
+}
+
+type License struct {
+	Key          string
+	Subscription string
+	Duration     time.Duration
+	used         bool
+}
+
+type ChatMessage struct {
+	Author    string `json:"author"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type session struct {
+	validated bool
+	username  string
+}
+
+// Server is a fake EpicAuth API backed by httptest.Server.
+type Server struct {
+	*httptest.Server
+
+	// PublicKey is the hex encoded key clients should trust.
+	PublicKey  string
+	privateKey ed25519.PrivateKey
+
+	mu        sync.Mutex
+	app       App
+	now       func() time.Time
+	sessions  map[string]*session
+	users     map[string]*User
+	licenses  map[string]*License
+	vars      map[string]string
+	files     map[string][]byte
+	blacklist map[string]bool
+	webhooks  map[string]string
+	chat      map[string][]ChatMessage
+	lastChat  map[string]time.Time
+	requests  []url.Values
+}
+
+// NewServer starts a fake server for app. Close it when done.
+func NewServer(app App) *Server {
+	if app.Name == "" {
+		app.Name = DefaultName
+	}
+	if app.OwnerID == "" {
+		app.OwnerID = DefaultOwnerID
+	}
+	if app.Version == "" {
+		app.Version = DefaultVersion
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic("epicauthtest: generating key: " + err.Error())
+	}
+
+	s := &Server{
+		PublicKey:  hex.EncodeToString(publicKey),
+		privateKey: privateKey,
+		app:        app,
+		now:        time.Now,
+		sessions:   make(map[string]*session),
+		users:      make(map[string]*User),
+		licenses:   make(map[string]*License),
+		vars:       make(map[string]string),
+		files:      make(map[string][]byte),
+		blacklist:  make(map[string]bool),
+		webhooks:   make(map[string]string),
+		chat:       make(map[string][]ChatMessage),
+		lastChat:   make(map[string]time.Time),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// SetClock changes the time used for signature timestamps, e.g. to test
+// clock skew handling.
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+func (s *Server) AddUser(user User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if user.Vars == nil {
+		user.Vars = make(map[string]string)
+	}
+	s.users[user.Username] = &user
+}
+
+// User returns a copy of a seeded or registered user.
+func (s *Server) User(username string) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[username]
+	if !ok {
+		return User{}, false
+	}
+	return *user, true
+}
+
+func (s *Server) AddLicense(license License) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.licenses[license.Key] = &license
+}
+
+// Ban bans a user, as if from the dashboard.
+func (s *Server) Ban(username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if user, ok := s.users[username]; ok {
+		user.Banned = true
+	}
+}
+
+// SetVar sets an application variable.
+func (s *Server) SetVar(name, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vars[name] = value
+}
+
+// PostChat adds a message to a chat channel, as if another user had
+// sent it.
+func (s *Server) PostChat(channel string, message ChatMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chat[channel] = append(s.chat[channel], message)
+}
+
+func (s *Server) AddFile(fileID string, contents []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[fileID] = contents
+}
+
+// Blacklist blocks a HWID or IP address.
+func (s *Server) Blacklist(hwidOrIP string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blacklist[hwidOrIP] = true
+}
+
+// SetWebhook makes webhook webID answer with response.
+func (s *Server) SetWebhook(webID, response string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.webhooks[webID] = response
+}
+
+// SetToken changes the token init requires, as if it was regenerated on
+// the dashboard.
+func (s *Server) SetToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.app.Token = token
+}
+
+// ExpireSessions ends every open session, as if the server restarted.
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = make(map[string]*session)
+}
+
+// Requests returns the form values of every request received so far.
+func (s *Server) Requests() []url.Values {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]url.Values(nil), s.requests...)
+}
+
+// Sign returns the hex signature the server would send for body at
+// timestamp.
+func (s *Server) Sign(body []byte, timestamp string) string {
+	return hex.EncodeToString(ed25519.Sign(s.privateKey, append([]byte(timestamp), body...)))
+}
+
+type response map[string]interface{}
+
+func fail(message string) response {
+	return response{"success": false, "message": message}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	form := r.PostForm
+	ip, _, _ := net.SplitHostPort(r.RemoteAddr)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, form)
+	var body []byte
+	if form.Get("name") != s.app.Name || form.Get("ownerid") != s.app.OwnerID {
+		body = []byte("EpicAuth_Invalid")
+	} else {
+		reply := s.handle(form, ip)
+		if nonce := form.Get("nonce"); nonce != "" && !s.app.NoNonce {
+			reply["nonce"] = nonce
+		}
+		body, _ = json.Marshal(reply)
+	}
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("x-signature-ed25519", s.Sign(body, timestamp))
+	w.Header().Set("x-signature-timestamp", timestamp)
+	w.Write(body)
+}
+
+// handle answers one request. s.mu is held.
+func (s *Server) handle(form url.Values, ip string) response {
+	requestType := form.Get("type")
+	if requestType == "init" {
+		return s.init(form)
+	}
+
+	sess, ok := s.sessions[form.Get("sessionid")]
+	if !ok {
+		return fail("Session not found. Use latest client.")
+	}
+
+	switch requestType {
+	case "login":
+		return s.login(sess, form.Get("username"), form.Get("pass"), form.Get("hwid"), ip)
+	case "register":
+		return s.register(sess, form, ip)
+	case "license":
+		return s.license(sess, form.Get("key"), form.Get("hwid"), ip)
+	case "upgrade":
+		return s.upgrade(form.Get("username"), form.Get("key"))
+	case "forgot":
+		user, ok := s.users[form.Get("username")]
+		if !ok || user.Email != form.Get("email") {
+			return fail("Email address doesn't match.")
+		}
+		return response{"success": true, "message": "Successfully sent email for password reset."}
+	case "var":
+		value, ok := s.vars[form.Get("varid")]
+		if !ok {
+			return fail("Variable not found.")
+		}
+		return response{"success": true, "message": value}
+	case "file":
+		contents, ok := s.files[form.Get("fileid")]
+		if !ok {
+			return fail("File not Found")
+		}
+		return response{"success": true, "message": "File download successful", "contents": hex.EncodeToString(contents)}
+	case "log":
+		return response{"success": true, "message": "Logged"}
+	case "checkblacklist":
+		if s.blacklist[form.Get("hwid")] || s.blacklist[ip] {
+			return response{"success": true, "message": "Client is blacklisted"}
+		}
+		return fail("Client is not blacklisted")
+	case "fetchStats":
+		return s.fetchStats()
+	case "fetchOnline":
+		users := []map[string]string{}
+		for _, other := range s.sessions {
+			if other.validated {
+				users = append(users, map[string]string{"credential": other.username})
+			}
+		}
+		if len(users) == 0 {
+			return fail("No online users found!")
+		}
+		return response{"success": true, "message": "Successfully fetched online users.", "users": users}
+	case "webhook":
+		result, ok := s.webhooks[form.Get("webid")]
+		if !ok {
+			return fail("Webhook Not Found.")
+		}
+		return response{"success": true, "message": "Webhook request successful", "response": result}
+	}
+
+	if !sess.validated {
+		return fail("Session is not validated")
+	}
+	user := s.users[sess.username]
+	if user.Banned {
+		return fail("The user is banned")
+	}
+
+	switch requestType {
+	case "check":
+		return response{"success": true, "message": "Session is validated."}
+	case "logout":
+		delete(s.sessions, form.Get("sessionid"))
+		return response{"success": true, "message": "Successfully logged out."}
+	case "getvar":
+		value, ok := user.Vars[form.Get("var")]
+		if !ok {
+			return fail("Variable not found for user")
+		}
+		return response{"success": true, "message": "Successfully retrieved variable", "response": value}
+	case "setvar":
+		user.Vars[form.Get("var")] = form.Get("data")
+		return response{"success": true, "message": "Successfully set variable"}
+	case "ban":
+		user.Banned = true
+		s.blacklist[user.HWID] = true
+		return response{"success": true, "message": "Successfully Banned User"}
+	case "changeUsername":
+		newUsername := form.Get("newUsername")
+		if _, taken := s.users[newUsername]; taken {
+			return fail("Username already taken, choose a different one")
+		}
+		delete(s.users, user.Username)
+		user.Username = newUsername
+		s.users[newUsername] = user
+		sess.username = newUsername
+		return response{"success": true, "message": "Successfully changed username"}
+	case "chatget":
+		messages := append([]ChatMessage{}, s.chat[form.Get("channel")]...)
+		return response{"success": true, "message": "Successfully retrieved chat messages", "messages": messages}
+	case "chatsend":
+		channel := form.Get("channel")
+		if s.now().Before(user.MutedUntil) {
+			return fail("You're muted from chat until " + user.MutedUntil.UTC().Format("Jan 2, 2006 3:04 PM") + " UTC")
+		}
+		if last, ok := s.lastChat[user.Username+"/"+channel]; ok && s.now().Sub(last) < s.app.ChatDelay {
+			return fail("Chat slower, you've hit the delay limit")
+		}
+		s.lastChat[user.Username+"/"+channel] = s.now()
+		s.chat[channel] = append(s.chat[channel], ChatMessage{
+			Author:    user.Username,
+			Message:   form.Get("message"),
+			Timestamp: s.now().Unix(),
+		})
+		return response{"success": true, "message": "Successfully sent chat message"}
+	}
+
+	return fail("Unhandled Type")
+}
+
+func (s *Server) init(form url.Values) response {
+	if form.Get("ver") != s.app.Version {
+		return response{"success": false, "message": "invalidver", "download": s.app.Download}
+	}
+	if s.app.Token != "" {
+		hash := sha256.Sum256([]byte(form.Get("token")))
+		if form.Get("token") != s.app.Token || form.Get("thash") != hex.EncodeToString(hash[:]) {
+			return fail("Invalid token")
+		}
+	}
+
+	id := make([]byte, 16)
+	rand.Read(id)
+	sessionID := hex.EncodeToString(id)
+	s.sessions[sessionID] = &session{}
+	return response{"success": true, "message": "Initialized", "sessionid": sessionID, "newSession": true}
+}
+
+func (s *Server) login(sess *session, username, password, hwid, ip string) response {
+	user, ok := s.users[username]
+	if !ok || user.Password != password {
+		return fail("Invalid username or password")
+	}
+	if user.Banned {
+		return fail("The user is banned")
+	}
+	if s.blacklist[hwid] || s.blacklist[ip] {
+		return fail("You've been blacklisted from this application")
+	}
+	if user.HWID == "" {
+		user.HWID = hwid
+	} else if user.HWID != hwid {
+		return fail("HWID doesn't match. Ask for a HWID reset")
+	}
+
+	now := s.now()
+	active := false
+	for _, sub := range user.Subscriptions {
+		if sub.Expiry.After(now) {
+			active = true
+		}
+	}
+	if !active {
+		return fail("No active subscription(s) found")
+	}
+
+	sess.validated = true
+	sess.username = user.Username
+	return response{"success": true, "message": "Logged in!", "info": s.userInfo(user, ip)}
+}
+
+func (s *Server) register(sess *session, form url.Values, ip string) response {
+	username := form.Get("username")
+	if _, taken := s.users[username]; taken {
+		return fail("Username already taken, choose a different one")
+	}
+	sub, message := s.redeem(form.Get("key"))
+	if message != "" {
+		return fail(message)
+	}
+
+	user := &User{
+		Username:      username,
+		Password:      form.Get("pass"),
+		Subscriptions: []Subscription{sub},
+		Vars:          make(map[string]string),
+	}
+	s.users[username] = user
+	return s.login(sess, username, user.Password, form.Get("hwid"), ip)
+}
+
+// license logs in with just a license key, creating the user on first use.
+func (s *Server) license(sess *session, key, hwid, ip string) response {
+	if _, ok := s.users[key]; !ok {
+		sub, message := s.redeem(key)
+		if message != "" {
+			return fail(message)
+		}
+		s.users[key] = &User{
+			Username:      key,
+			Password:      key,
+			Subscriptions: []Subscription{sub},
+			Vars:          make(map[string]string),
+		}
+	}
+	return s.login(sess, key, key, hwid, ip)
+}
+
+func (s *Server) upgrade(username, key string) response {
+	user, ok := s.users[username]
+	if !ok {
+		return fail("Invalid username")
+	}
+	sub, message := s.redeem(key)
+	if message != "" {
+		return fail(message)
+	}
+	user.Subscriptions = append(user.Subscriptions, sub)
+	return response{"success": true, "message": "Upgraded successfully"}
+}
+
+// redeem marks a license as used and returns the subscription it grants,
+// or a failure message.
+func (s *Server) redeem(key string) (Subscription, string) {
+	license, ok := s.licenses[key]
+	if !ok {
+		return Subscription{}, "Invalid license key"
+	}
+	if license.used {
+		return Subscription{}, "License key has already been used"
+	}
+	license.used = true
+	return Subscription{Name: license.Subscription, Key: key, Expiry: s.now().Add(license.Duration)}, ""
+}
+
+func (s *Server) userInfo(user *User, ip string) map[string]interface{} {
+	now := s.now()
+	subscriptions := []map[string]interface{}{}
+	for _, sub := range user.Subscriptions {
+		subscriptions = append(subscriptions, map[string]interface{}{
+			"subscription": sub.Name,
+			"key":          sub.Key,
+			"expiry":       strconv.FormatInt(sub.Expiry.Unix(), 10),
+			"timeleft":     int64(sub.Expiry.Sub(now).Seconds()),
+		})
+	}
+	return map[string]interface{}{
+		"username":      user.Username,
+		"subscriptions": subscriptions,
+		"ip":            ip,
+		"hwid":          user.HWID,
+		"createdate":    strconv.FormatInt(now.Unix(), 10),
+		"lastlogin":     strconv.FormatInt(now.Unix(), 10),
+	}
+}
+
+func (s *Server) fetchStats() response {
+	online := 0
+	for _, sess := range s.sessions {
+		if sess.validated {
+			online++
+		}
+	}
+	return response{"success": true, "message": "Successfully fetched stats", "appinfo": map[string]string{
+		"numUsers":          strconv.Itoa(len(s.users)),
+		"numOnlineUsers":    strconv.Itoa(online),
+		"numKeys":           strconv.Itoa(len(s.licenses)),
+		"version":           s.app.Version,
+		"customerPanelLink": "https://EpicAuth.cc/panel/" + s.app.OwnerID + "/" + s.app.Name + "/",
+	}}
+}
@@ -0,0 +1,88 @@
+package EpicAuth
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidApp         = errors.New("EpicAuth: application not set up properly")
+	ErrNotInitialized     = errors.New("EpicAuth: application not initialized")
+	ErrAlreadyInitialized = errors.New("EpicAuth: application already initialized")
+	ErrInvalidVersion     = errors.New("EpicAuth: invalid application version")
+	ErrHWIDMismatch       = errors.New("EpicAuth: hwid does not match")
+	ErrSignatureInvalid   = errors.New("EpicAuth: response signature invalid")
+	ErrClockSkew          = errors.New("EpicAuth: time difference with server is too large")
+	ErrNonceMismatch      = errors.New("EpicAuth: response does not belong to the request")
+	ErrSessionExpired     = errors.New("EpicAuth: session expired or invalid")
+	ErrBanned             = errors.New("EpicAuth: user is banned or blacklisted")
+	ErrInvalidResponse    = errors.New("EpicAuth: malformed response")
+	ErrFileNotFound       = errors.New("EpicAuth: file not found")
+	ErrVarNotFound        = errors.New("EpicAuth: variable not found")
+	ErrMuted              = errors.New("EpicAuth: user is muted from chat")
+	ErrRateLimited        = errors.New("EpicAuth: sending chat messages too fast")
+	ErrTokenMissing       = errors.New("EpicAuth: token not found")
+	ErrTokenInvalid       = errors.New("EpicAuth: token is malformed")
+	ErrTokenRejected      = errors.New("EpicAuth: token rejected by the server")
+	ErrOffline            = errors.New("EpicAuth: client is running offline")
+	ErrGraceExpired       = errors.New("EpicAuth: offline grace period has expired")
+)
+
+// APIError is returned when the server answers a request with
+// success=false. Err holds the matching sentinel error, if any, so
+// callers can use errors.Is.
+type APIError struct {
+	Type    string // request type, e.g. "login"
+	Message string // the server's message field
+	Err     error
+
+	// Download is the new version's download link for ErrInvalidVersion.
+	Download string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return "EpicAuth: " + e.Type + " failed"
+	}
+	return "EpicAuth: " + e.Type + ": " + e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+func newAPIError(requestType, message string) *APIError {
+	return &APIError{Type: requestType, Message: message, Err: classify(message)}
+}
+
+// classify maps a server message to one of the sentinel errors.
+func classify(message string) error {
+	msg := strings.ToLower(message)
+	switch {
+	case msg == "invalidver":
+		return ErrInvalidVersion
+	case strings.Contains(msg, "hwid") && (strings.Contains(msg, "match") || strings.Contains(msg, "reset")):
+		return ErrHWIDMismatch
+	case strings.Contains(msg, "not blacklisted"):
+		return nil
+	case strings.Contains(msg, "banned"), strings.Contains(msg, "blacklisted"):
+		return ErrBanned
+	case strings.Contains(msg, "session") && (strings.Contains(msg, "not found") || strings.Contains(msg, "expired") ||
+		strings.Contains(msg, "invalid") || strings.Contains(msg, "not validated") || strings.Contains(msg, "ended")):
+		return ErrSessionExpired
+	case strings.Contains(msg, "file") && strings.Contains(msg, "not found"):
+		return ErrFileNotFound
+	case strings.Contains(msg, "variable") && strings.Contains(msg, "not found"):
+		return ErrVarNotFound
+	case strings.Contains(msg, "token"):
+		return ErrTokenRejected
+	case strings.Contains(msg, "muted"):
+		return ErrMuted
+	case strings.Contains(msg, "delay limit"), strings.Contains(msg, "chat slower"):
+		return ErrRateLimited
+	case strings.Contains(msg, "application") && (strings.Contains(msg, "not found") || strings.Contains(msg, "does not exist") ||
+		strings.Contains(msg, "paused") || strings.Contains(msg, "disabled")):
+		return ErrInvalidApp
+	}
+	return nil
+}
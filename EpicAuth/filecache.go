@@ -0,0 +1,214 @@
+package EpicAuth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultFileCacheSize is the file cache's size limit unless
+// FileCacheConfig.MaxSize says otherwise.
+const DefaultFileCacheSize = 256 << 20
+
+// DefaultFileCacheMaxAge is how long a cached file is used without an
+// expected SHA-256 unless FileCacheConfig.MaxAge says otherwise.
+const DefaultFileCacheMaxAge = 24 * time.Hour
+
+// FileCacheConfig configures WithFileCache.
+type FileCacheConfig struct {
+	// Dir holds the cached files. Defaults to a directory named after the
+	// application in the user's cache directory.
+	Dir string
+	// MaxSize is how many bytes the cache may hold. The least recently
+	// used files are evicted past it, and larger files aren't cached.
+	// Defaults to DefaultFileCacheSize.
+	MaxSize int64
+	// MaxAge is how long after it was downloaded a cached file is used
+	// when DownloadOptions.SHA256 isn't given. Files checked against a
+	// SHA-256 are used for as long as they match. Defaults to
+	// DefaultFileCacheMaxAge.
+	MaxAge time.Duration
+	// Key encrypts the cached files. Defaults to a key derived from the
+	// application and the HWID, which ties the files to this machine.
+	Key []byte
+}
+
+// fileCache is a FileCacheConfig with its defaults applied. mu keeps two
+// downloads from evicting the same files at once.
+type fileCache struct {
+	cfg FileCacheConfig
+	mu  sync.Mutex
+}
+
+// ClearFileCache removes every cached file.
+func (c *Client) ClearFileCache() error {
+	if c.files == nil {
+		return nil
+	}
+	dir, err := c.fileCacheDir()
+	if err != nil {
+		return err
+	}
+	c.files.mu.Lock()
+	defer c.files.mu.Unlock()
+	return os.RemoveAll(dir)
+}
+
+// downloadCached writes a cached copy of fileID to w. A copy is only used
+// if it has the expected SHA-256, or is younger than MaxAge when no
+// SHA-256 is given, and the session is still valid, which costs a check
+// request rather than the whole file. ok is false if there is no usable
+// copy.
+func (c *Client) downloadCached(ctx context.Context, fileID string, w io.Writer, opts DownloadOptions) (n int64, ok bool, err error) {
+	path, contentHash := c.findCachedFile(fileID)
+	if path == "" || (opts.SHA256 != "" && !strings.EqualFold(opts.SHA256, contentHash)) {
+		return 0, false, nil
+	}
+	contents, stored, err := c.readCachedFile(path, fileID, contentHash)
+	if err != nil {
+		os.Remove(path)
+		return 0, false, nil
+	}
+	if opts.SHA256 == "" && c.now().Sub(stored) > c.files.cfg.MaxAge {
+		return 0, false, nil
+	}
+
+	// Offline, the cached copy is served for as long as the grace
+	// period lasts.
+	if _, err := c.CheckContext(ctx); err != nil && !errors.Is(err, ErrOffline) {
+		return 0, true, err
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	pw := &progressWriter{ctx: ctx, w: w, total: int64(len(contents)), progress: opts.Progress}
+	written, err := pw.Write(contents)
+	return int64(written), true, err
+}
+
+// findCachedFile returns the cached copy of fileID and its SHA-256, or ""
+// if there is none.
+func (c *Client) findCachedFile(fileID string) (path, contentHash string) {
+	dir, err := c.fileCacheDir()
+	if err != nil {
+		return "", ""
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, cachedFilePrefix(fileID)+"*"))
+	for _, match := range matches {
+		if contentHash := strings.TrimPrefix(filepath.Base(match), cachedFilePrefix(fileID)); len(contentHash) == sha256.Size*2 {
+			return match, contentHash
+		}
+	}
+	return "", ""
+}
+
+// readCachedFile returns a cached file's contents and when it was stored.
+func (c *Client) readCachedFile(path, fileID, contentHash string) (contents []byte, stored time.Time, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	aead, err := c.localCipher("files", c.files.cfg.Key)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	plaintext, err := unseal(aead, data, []byte(fileID+"\x00"+contentHash))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if len(plaintext) < 8 {
+		return nil, time.Time{}, io.ErrUnexpectedEOF
+	}
+	return plaintext[8:], time.Unix(0, int64(binary.BigEndian.Uint64(plaintext))), nil
+}
+
+// storeCachedFile caches contents as the current copy of fileID,
+// replacing older copies, then evicts files until the cache fits.
+func (c *Client) storeCachedFile(fileID string, contents []byte, contentHash string) error {
+	dir, err := c.fileCacheDir()
+	if err != nil {
+		return err
+	}
+	aead, err := c.localCipher("files", c.files.cfg.Key)
+	if err != nil {
+		return err
+	}
+	// The store time is sealed with the contents, since the file's
+	// modification time tracks when it was last used.
+	plaintext := make([]byte, 8, 8+len(contents))
+	binary.BigEndian.PutUint64(plaintext, uint64(c.now().UnixNano()))
+	data, err := seal(aead, append(plaintext, contents...), []byte(fileID+"\x00"+contentHash))
+	if err != nil {
+		return err
+	}
+
+	c.files.mu.Lock()
+	defer c.files.mu.Unlock()
+	path := filepath.Join(dir, cachedFilePrefix(fileID)+contentHash)
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+	old, _ := filepath.Glob(filepath.Join(dir, cachedFilePrefix(fileID)+"*"))
+	for _, match := range old {
+		if match != path {
+			os.Remove(match)
+		}
+	}
+	return c.evictCachedFiles(dir)
+}
+
+// evictCachedFiles removes the least recently used files until the cache
+// is no larger than MaxSize. Files are touched when they are served, so
+// their modification time is when they were last used.
+func (c *Client) evictCachedFiles(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var total int64
+	var files []os.FileInfo
+	for _, entry := range entries {
+		if entry.Mode().IsRegular() && !strings.Contains(entry.Name(), ".tmp") {
+			files = append(files, entry)
+			total += entry.Size()
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+	for _, file := range files {
+		if total <= c.files.cfg.MaxSize {
+			break
+		}
+		if err := os.Remove(filepath.Join(dir, file.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= file.Size()
+	}
+	return nil
+}
+
+func (c *Client) fileCacheDir() (string, error) {
+	if c.files.cfg.Dir != "" {
+		return c.files.cfg.Dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "EpicAuth", "Files", c.name+"-"+c.ownerID), nil
+}
+
+// cachedFilePrefix names a file's cache entries without revealing its ID.
+func cachedFilePrefix(fileID string) string {
+	sum := sha256.Sum256([]byte(fileID))
+	return hex.EncodeToString(sum[:16]) + "."
+}
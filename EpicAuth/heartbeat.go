@@ -0,0 +1,170 @@
+package EpicAuth
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// HeartbeatConfig configures StartHeartbeat. Callbacks run on the
+// heartbeat goroutine and may be nil.
+type HeartbeatConfig struct {
+	// Interval between checks. Defaults to one minute.
+	Interval time.Duration
+
+	// OnSessionInvalid is called when the server no longer accepts the
+	// session, or the offline grace period has run out. The heartbeat
+	// stops afterwards.
+	OnSessionInvalid func(err error)
+	// OnBanned is called when the user has been banned or blacklisted.
+	// The heartbeat stops afterwards.
+	OnBanned func(err error)
+	// OnSubscriptionExpired is called once for each of the user's
+	// subscriptions as its expiry passes.
+	OnSubscriptionExpired func(sub SubscriptionInfo)
+}
+
+type HeartbeatEventKind int
+
+const (
+	HeartbeatOK HeartbeatEventKind = iota
+	// HeartbeatError is a check that failed for a transient reason, e.g.
+	// the network. The heartbeat keeps going.
+	HeartbeatError
+	HeartbeatSessionInvalid
+	HeartbeatBanned
+	HeartbeatSubscriptionExpired
+)
+
+type HeartbeatEvent struct {
+	Kind         HeartbeatEventKind
+	Time         time.Time
+	Err          error             // set for HeartbeatError, HeartbeatSessionInvalid and HeartbeatBanned
+	Subscription *SubscriptionInfo // set for HeartbeatSubscriptionExpired
+}
+
+// Heartbeat periodically checks a client's session. Create one with
+// Client.StartHeartbeat. Do not call Stop from a heartbeat callback.
+type Heartbeat struct {
+	*poller
+	c      *Client
+	cfg    HeartbeatConfig
+	events chan HeartbeatEvent
+
+	expired map[string]bool
+}
+
+// StartHeartbeat starts checking the session every cfg.Interval until ctx
+// is done, Stop is called, the session turns out invalid or Logout is
+// called. Starting a new heartbeat stops the previous one.
+func (c *Client) StartHeartbeat(ctx context.Context, cfg HeartbeatConfig) (*Heartbeat, error) {
+	if err := c.CheckInit(); err != nil {
+		return nil, err
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+
+	h := &Heartbeat{
+		poller:  newPoller(ctx),
+		c:       c,
+		cfg:     cfg,
+		events:  make(chan HeartbeatEvent, 16),
+		expired: make(map[string]bool),
+	}
+
+	c.mu.Lock()
+	previous := c.heartbeat
+	c.heartbeat = h
+	c.mu.Unlock()
+	if previous != nil {
+		previous.cancel()
+	}
+
+	h.start(cfg.Interval, h.beat, h.stopped)
+	return h, nil
+}
+
+// Events returns the heartbeat's events. Events are dropped rather than
+// block the heartbeat if nobody reads them. The channel is closed when
+// the heartbeat stops.
+func (h *Heartbeat) Events() <-chan HeartbeatEvent {
+	return h.events
+}
+
+func (h *Heartbeat) beat(ctx context.Context) error {
+	h.checkSubscriptions()
+	return h.checkSession(ctx)
+}
+
+// stopped closes the events and forgets the heartbeat once it stops.
+func (h *Heartbeat) stopped() {
+	close(h.events)
+	h.c.mu.Lock()
+	if h.c.heartbeat == h {
+		h.c.heartbeat = nil
+	}
+	h.c.mu.Unlock()
+}
+
+// checkSession checks the session once and reports the result.
+func (h *Heartbeat) checkSession(ctx context.Context) error {
+	_, err := h.c.CheckContext(ctx)
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	switch {
+	case err == nil:
+		h.emit(HeartbeatEvent{Kind: HeartbeatOK})
+	case errors.Is(err, ErrBanned):
+		h.emit(HeartbeatEvent{Kind: HeartbeatBanned, Err: err})
+		if h.cfg.OnBanned != nil {
+			h.cfg.OnBanned(err)
+		}
+	case sessionEnded(err):
+		h.emit(HeartbeatEvent{Kind: HeartbeatSessionInvalid, Err: err})
+		if h.cfg.OnSessionInvalid != nil {
+			h.cfg.OnSessionInvalid(err)
+		}
+	default:
+		h.emit(HeartbeatEvent{Kind: HeartbeatError, Err: err})
+	}
+	return err
+}
+
+func (h *Heartbeat) checkSubscriptions() {
+	now := h.c.serverNow()
+	for _, sub := range h.c.User().Subscriptions {
+		expiresAt, err := sub.ExpiresAt()
+		if err != nil || now.Before(expiresAt) || h.expired[sub.Key+"/"+sub.Subscription] {
+			continue
+		}
+		h.expired[sub.Key+"/"+sub.Subscription] = true
+
+		sub := sub
+		h.emit(HeartbeatEvent{Kind: HeartbeatSubscriptionExpired, Subscription: &sub})
+		if h.cfg.OnSubscriptionExpired != nil {
+			h.cfg.OnSubscriptionExpired(sub)
+		}
+	}
+}
+
+func (h *Heartbeat) emit(event HeartbeatEvent) {
+	event.Time = h.c.now()
+	select {
+	case h.events <- event:
+	default:
+	}
+}
+
+// stopHeartbeat stops the running heartbeat, if any, without waiting.
+func (c *Client) stopHeartbeat() {
+	c.mu.Lock()
+	h := c.heartbeat
+	c.heartbeat = nil
+	c.mu.Unlock()
+	if h != nil {
+		h.cancel()
+	}
+}
@@ -0,0 +1,163 @@
+package EpicAuth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	EpicAuth "EpicAuth/EpicAuth"
+	"EpicAuth/EpicAuth/epicauthtest"
+)
+
+const heartbeatInterval = 10 * time.Millisecond
+
+// waitFor reads events from h until one of kind arrives.
+func waitFor(t *testing.T, h *EpicAuth.Heartbeat, kind EpicAuth.HeartbeatEventKind) EpicAuth.HeartbeatEvent {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-h.Events():
+			if !ok {
+				t.Fatalf("heartbeat stopped before event %d", kind)
+			}
+			if event.Kind == kind {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for event %d", kind)
+		}
+	}
+}
+
+func waitStopped(t *testing.T, h *EpicAuth.Heartbeat) {
+	t.Helper()
+	select {
+	case <-h.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("heartbeat did not stop")
+	}
+}
+
+func TestHeartbeatSessionInvalid(t *testing.T) {
+	s := newServer(t)
+	c := loggedIn(t, s)
+
+	var invalid error
+	h, err := c.StartHeartbeat(context.Background(), EpicAuth.HeartbeatConfig{
+		Interval:         heartbeatInterval,
+		OnSessionInvalid: func(err error) { invalid = err },
+	})
+	if err != nil {
+		t.Fatalf("StartHeartbeat() error = %v", err)
+	}
+	waitFor(t, h, EpicAuth.HeartbeatOK)
+
+	s.ExpireSessions()
+	event := waitFor(t, h, EpicAuth.HeartbeatSessionInvalid)
+	waitStopped(t, h)
+	if !errors.Is(event.Err, EpicAuth.ErrSessionExpired) || invalid != event.Err {
+		t.Errorf("event error = %v, callback error = %v", event.Err, invalid)
+	}
+}
+
+func TestHeartbeatBanned(t *testing.T) {
+	s := newServer(t)
+	c := loggedIn(t, s)
+
+	var banned error
+	h, err := c.StartHeartbeat(context.Background(), EpicAuth.HeartbeatConfig{
+		Interval: heartbeatInterval,
+		OnBanned: func(err error) { banned = err },
+	})
+	if err != nil {
+		t.Fatalf("StartHeartbeat() error = %v", err)
+	}
+
+	s.Ban("alice")
+	waitFor(t, h, EpicAuth.HeartbeatBanned)
+	waitStopped(t, h)
+	if !errors.Is(banned, EpicAuth.ErrBanned) {
+		t.Errorf("OnBanned error = %v, want %v", banned, EpicAuth.ErrBanned)
+	}
+}
+
+func TestHeartbeatSubscriptionExpired(t *testing.T) {
+	s := newServer(t)
+	s.AddUser(epicauthtest.User{
+		Username: "bob",
+		Password: "pw",
+		Subscriptions: []epicauthtest.Subscription{
+			{Name: "default", Key: "ACTIVE", Expiry: time.Now().Add(time.Hour)},
+			{Name: "trial", Key: "EXPIRED", Expiry: time.Now().Add(-time.Hour)},
+		},
+	})
+	c := initClient(t, s)
+	if _, err := c.Login("bob", "pw"); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	var expired []string
+	h, err := c.StartHeartbeat(context.Background(), EpicAuth.HeartbeatConfig{
+		Interval:              heartbeatInterval,
+		OnSubscriptionExpired: func(sub EpicAuth.SubscriptionInfo) { expired = append(expired, sub.Key) },
+	})
+	if err != nil {
+		t.Fatalf("StartHeartbeat() error = %v", err)
+	}
+	event := waitFor(t, h, EpicAuth.HeartbeatSubscriptionExpired)
+	if event.Subscription == nil || event.Subscription.Subscription != "trial" {
+		t.Errorf("event subscription = %+v, want trial", event.Subscription)
+	}
+
+	// Let a few more ticks pass; the expiry must only be reported once.
+	waitFor(t, h, EpicAuth.HeartbeatOK)
+	waitFor(t, h, EpicAuth.HeartbeatOK)
+	h.Stop()
+	if len(expired) != 1 || expired[0] != "EXPIRED" {
+		t.Errorf("OnSubscriptionExpired calls = %v, want [EXPIRED]", expired)
+	}
+}
+
+func TestHeartbeatStop(t *testing.T) {
+	s := newServer(t)
+	config := EpicAuth.HeartbeatConfig{Interval: heartbeatInterval}
+
+	t.Run("context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		h, err := loggedIn(t, s).StartHeartbeat(ctx, config)
+		if err != nil {
+			t.Fatalf("StartHeartbeat() error = %v", err)
+		}
+		cancel()
+		waitStopped(t, h)
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		c := loggedIn(t, s)
+		h, err := c.StartHeartbeat(context.Background(), config)
+		if err != nil {
+			t.Fatalf("StartHeartbeat() error = %v", err)
+		}
+		if err := c.Logout(); err != nil {
+			t.Fatalf("Logout() error = %v", err)
+		}
+		waitStopped(t, h)
+	})
+
+	t.Run("restart", func(t *testing.T) {
+		c := loggedIn(t, s)
+		first, _ := c.StartHeartbeat(context.Background(), config)
+		second, _ := c.StartHeartbeat(context.Background(), config)
+		waitStopped(t, first)
+		waitFor(t, second, EpicAuth.HeartbeatOK)
+		second.Stop()
+	})
+
+	t.Run("not initialized", func(t *testing.T) {
+		if _, err := newClient(t, s).StartHeartbeat(context.Background(), config); !errors.Is(err, EpicAuth.ErrNotInitialized) {
+			t.Errorf("StartHeartbeat() error = %v, want %v", err, EpicAuth.ErrNotInitialized)
+		}
+	})
+}
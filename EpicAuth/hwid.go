@@ -0,0 +1,424 @@
+package EpicAuth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrHWIDUnavailable is returned by providers that find nothing to
+// identify the machine by.
+var ErrHWIDUnavailable = errors.New("EpicAuth: hwid unavailable")
+
+// HWIDProvider identifies the machine a user logs in from.
+type HWIDProvider interface {
+	HWID() (string, error)
+}
+
+// HWIDFunc adapts a function to HWIDProvider.
+type HWIDFunc func() (string, error)
+
+func (f HWIDFunc) HWID() (string, error) {
+	return f()
+}
+
+// hostRoot is prepended to the /etc, /proc and /sys paths read by the
+// Linux providers.
+var hostRoot = "/"
+
+func readHostFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(hostRoot, path))
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("%w: %s is empty", ErrHWIDUnavailable, path)
+	}
+	return value, nil
+}
+
+// Linux providers.
+var (
+	// MachineID reads the systemd machine ID.
+	MachineID HWIDProvider = HWIDFunc(func() (string, error) {
+		return readHostFile("/etc/machine-id")
+	})
+
+	// ProductUUID reads the DMI product UUID. It is usually only
+	// readable by root.
+	ProductUUID HWIDProvider = HWIDFunc(func() (string, error) {
+		return readHostFile("/sys/class/dmi/id/product_uuid")
+	})
+
+	// MACAddresses lists the addresses of the physical network
+	// interfaces.
+	MACAddresses HWIDProvider = HWIDFunc(macAddresses)
+
+	// DiskSerials lists the serial numbers of the block devices.
+	DiskSerials HWIDProvider = HWIDFunc(diskSerials)
+
+	// CPUInfo describes the CPU model and core count.
+	CPUInfo HWIDProvider = HWIDFunc(cpuInfo)
+)
+
+// Windows and macOS providers.
+var (
+	// WindowsSID returns the security identifier of the current user.
+	WindowsSID HWIDProvider = HWIDFunc(windowsSID)
+
+	// PlatformSerial returns the Mac's serial number.
+	PlatformSerial HWIDProvider = HWIDFunc(platformSerial)
+)
+
+// DefaultHWIDProvider returns the provider clients use unless told
+// otherwise: MachineID on Linux, WindowsSID on Windows and PlatformSerial
+// on macOS.
+func DefaultHWIDProvider() HWIDProvider {
+	switch runtime.GOOS {
+	case "linux":
+		return MachineID
+	case "windows":
+		return WindowsSID
+	case "darwin":
+		return PlatformSerial
+	default:
+		return HWIDFunc(func() (string, error) {
+			return "", fmt.Errorf("%w: unsupported OS %s", ErrHWIDUnavailable, runtime.GOOS)
+		})
+	}
+}
+
+func macAddresses() (string, error) {
+	dir := filepath.Join(hostRoot, "/sys/class/net")
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var addresses []string
+	for _, entry := range entries {
+		// Virtual interfaces (bridges, veths, tunnels) link into
+		// /sys/devices/virtual and come and go.
+		if target, err := os.Readlink(filepath.Join(dir, entry.Name())); err == nil && strings.Contains(target, "/virtual/") {
+			continue
+		}
+		address, err := readHostFile(filepath.Join("/sys/class/net", entry.Name(), "address"))
+		if err != nil || address == "00:00:00:00:00:00" {
+			continue
+		}
+		addresses = append(addresses, address)
+	}
+	if len(addresses) == 0 {
+		return "", fmt.Errorf("%w: no physical network interfaces", ErrHWIDUnavailable)
+	}
+	sort.Strings(addresses)
+	return strings.Join(addresses, ","), nil
+}
+
+func diskSerials() (string, error) {
+	entries, err := ioutil.ReadDir(filepath.Join(hostRoot, "/sys/block"))
+	if err != nil {
+		return "", err
+	}
+
+	var serials []string
+	for _, entry := range entries {
+		device := filepath.Join("/sys/block", entry.Name(), "device")
+		serial, err := readHostFile(filepath.Join(device, "serial"))
+		if err != nil {
+			serial, err = readHostFile(filepath.Join(device, "wwid"))
+		}
+		if err == nil {
+			serials = append(serials, serial)
+		}
+	}
+	if len(serials) == 0 {
+		return "", fmt.Errorf("%w: no disk serial numbers", ErrHWIDUnavailable)
+	}
+	sort.Strings(serials)
+	return strings.Join(serials, ","), nil
+}
+
+func cpuInfo() (string, error) {
+	f, err := os.Open(filepath.Join(hostRoot, "/proc/cpuinfo"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// Only the fields that stay put; "cpu MHz" and friends change all
+	// the time.
+	fields := map[string]string{}
+	processors := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "processor":
+			processors++
+		case "vendor_id", "model name", "cpu family", "model", "stepping":
+			if _, seen := fields[key]; !seen {
+				fields[key] = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if len(fields) == 0 {
+		return "", fmt.Errorf("%w: no CPU information", ErrHWIDUnavailable)
+	}
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%d", fields["vendor_id"], fields["model name"], fields["cpu family"],
+		fields["model"], fields["stepping"], processors), nil
+}
+
+func windowsSID() (string, error) {
+	out, err := exec.Command("whoami", "/user", "/fo", "csv", "/nh").Output()
+	if err != nil {
+		return "", err
+	}
+	return parseWhoami(out)
+}
+
+// parseWhoami extracts the SID from `whoami /user /fo csv /nh` output,
+// a single "DOMAIN\user","S-1-5-..." line.
+func parseWhoami(out []byte) (string, error) {
+	record, err := csv.NewReader(strings.NewReader(string(out))).Read()
+	if err != nil {
+		return "", err
+	}
+	if len(record) < 2 || !strings.HasPrefix(record[1], "S-") {
+		return "", fmt.Errorf("%w: unexpected whoami output", ErrHWIDUnavailable)
+	}
+	return record[1], nil
+}
+
+func platformSerial() (string, error) {
+	out, err := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output()
+	if err != nil {
+		return "", err
+	}
+	return parseIoreg(out, "IOPlatformSerialNumber")
+}
+
+// parseIoreg returns the value of key from ioreg output, where it
+// appears as `"key" = "value"`.
+func parseIoreg(out []byte, key string) (string, error) {
+	for _, line := range strings.Split(string(out), "\n") {
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || strings.Trim(strings.TrimSpace(name), `"`) != key {
+			continue
+		}
+		if value = strings.Trim(strings.TrimSpace(value), `"`); value != "" {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s not found in ioreg output", ErrHWIDUnavailable, key)
+}
+
+// HWIDComponent is one named input to a CompositeHWID.
+type HWIDComponent struct {
+	Name     string
+	Provider HWIDProvider
+}
+
+// LinuxHWIDComponents returns every built-in Linux provider.
+func LinuxHWIDComponents() []HWIDComponent {
+	return []HWIDComponent{
+		{"machine-id", MachineID},
+		{"product-uuid", ProductUUID},
+		{"mac", MACAddresses},
+		{"disk", DiskSerials},
+		{"cpu", CPUInfo},
+	}
+}
+
+// CompositeHWID hashes several components into one HWID. The first HWID
+// it computes is kept as a baseline, and is returned again as long as no
+// more than Tolerance components differ from it, so that e.g. replacing
+// a network card doesn't lock the user out. Tolerated changes are taken
+// into the baseline, so changes made one at a time keep the HWID.
+type CompositeHWID struct {
+	Components []HWIDComponent
+	Tolerance  int
+	// BaselinePath is where the baseline is kept between runs. If empty
+	// it only lives as long as the provider.
+	BaselinePath string
+	// Key encrypts the baseline file. Defaults to a key built into the
+	// package, which only hides the file's contents; the baseline's ID is
+	// always checked against the components it was computed from before
+	// it is used.
+	Key []byte
+
+	mu       sync.Mutex
+	baseline *hwidBaseline
+}
+
+// hwidBaseline is the HWID, the component hashes it was computed from,
+// and the component hashes last seen.
+type hwidBaseline struct {
+	ID         string            `json:"id"`
+	Origin     map[string]string `json:"origin"`
+	Components map[string]string `json:"components"`
+}
+
+// NewCompositeHWID returns a CompositeHWID over components that tolerates
+// one changed component and keeps its baseline in the user's config
+// directory.
+func NewCompositeHWID(components ...HWIDComponent) *CompositeHWID {
+	p := &CompositeHWID{Components: components, Tolerance: 1}
+	if dir, err := os.UserConfigDir(); err == nil {
+		p.BaselinePath = filepath.Join(dir, "EpicAuth", "hwid.dat")
+	}
+	return p
+}
+
+func (p *CompositeHWID) HWID() (string, error) {
+	current := make(map[string]string, len(p.Components))
+	found := false
+	for _, component := range p.Components {
+		value, err := component.Provider.HWID()
+		if err != nil {
+			current[component.Name] = ""
+			continue
+		}
+		sum := sha256.Sum256([]byte(component.Name + "\x00" + value))
+		current[component.Name] = hex.EncodeToString(sum[:])
+		found = true
+	}
+	if !found {
+		return "", fmt.Errorf("%w: no component could be read", ErrHWIDUnavailable)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.baseline == nil && p.BaselinePath != "" {
+		p.baseline = p.loadBaseline()
+	}
+	if p.baseline != nil && p.matches(current) {
+		if sameComponents(p.baseline.Components, current) {
+			return p.baseline.ID, nil
+		}
+		p.baseline.Components = current
+	} else {
+		p.baseline = &hwidBaseline{ID: compositeID(current), Origin: current, Components: current}
+	}
+	if p.BaselinePath != "" {
+		if err := p.saveBaseline(); err != nil {
+			return "", err
+		}
+	}
+	return p.baseline.ID, nil
+}
+
+func sameComponents(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, sum := range a {
+		if b[name] != sum {
+			return false
+		}
+	}
+	return true
+}
+
+// compositeID hashes the component hashes into the HWID.
+func compositeID(components map[string]string) string {
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s=%s\n", name, components[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadBaseline reads the baseline file. A file that can't be decrypted,
+// or whose ID isn't the hash of its origin components, e.g. because the
+// ID was copied from another machine, is ignored.
+func (p *CompositeHWID) loadBaseline() *hwidBaseline {
+	data, err := ioutil.ReadFile(p.BaselinePath)
+	if err != nil {
+		return nil
+	}
+	aead, err := newFileCipher(p.baselineKey())
+	if err != nil {
+		return nil
+	}
+	plaintext, err := unseal(aead, data, nil)
+	if err != nil {
+		return nil
+	}
+	var baseline hwidBaseline
+	if json.Unmarshal(plaintext, &baseline) != nil || baseline.ID != compositeID(baseline.Origin) {
+		return nil
+	}
+	return &baseline
+}
+
+func (p *CompositeHWID) saveBaseline() error {
+	plaintext, err := json.Marshal(p.baseline)
+	if err != nil {
+		return err
+	}
+	aead, err := newFileCipher(p.baselineKey())
+	if err != nil {
+		return err
+	}
+	data, err := seal(aead, plaintext, nil)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p.BaselinePath, data)
+}
+
+func (p *CompositeHWID) baselineKey() []byte {
+	if p.Key != nil {
+		return p.Key
+	}
+	return []byte("EpicAuth hwid baseline")
+}
+
+// matches reports whether current is close enough to the baseline: at
+// most Tolerance components changed and at least one readable component
+// stayed the same. A baseline over different components never matches.
+func (p *CompositeHWID) matches(current map[string]string) bool {
+	if len(current) != len(p.baseline.Components) {
+		return false
+	}
+	changed, same := 0, 0
+	for name, sum := range current {
+		old, ok := p.baseline.Components[name]
+		switch {
+		case !ok:
+			return false
+		case old != sum:
+			changed++
+		case sum != "":
+			same++
+		}
+	}
+	return changed <= p.Tolerance && same > 0
+}
@@ -0,0 +1,199 @@
+package EpicAuth
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeHost builds a /etc, /sys and /proc tree for the Linux providers and
+// points hostRoot at it for the duration of the test.
+func fakeHost(t *testing.T, files map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	for path, contents := range files {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := hostRoot
+	hostRoot = root
+	t.Cleanup(func() { hostRoot = old })
+}
+
+func TestLinuxProviders(t *testing.T) {
+	fakeHost(t, map[string]string{
+		"etc/machine-id":                      "0123456789abcdef\n",
+		"sys/class/dmi/id/product_uuid":       "4C4C4544-0042\n",
+		"sys/class/net/eth1/address":          "aa:bb:cc:00:00:02\n",
+		"sys/class/net/eth0/address":          "aa:bb:cc:00:00:01\n",
+		"sys/class/net/dummy/address":         "00:00:00:00:00:00\n",
+		"sys/devices/virtual/net/lo/address":  "00:00:00:00:00:00\n",
+		"sys/devices/virtual/net/br0/address": "02:42:ac:11:00:01\n",
+		"sys/block/sda/device/serial":         "S1\n",
+		"sys/block/nvme0n1/device/wwid":       "eui.0025\n",
+		"sys/block/loop0/size":                "0\n",
+		"proc/cpuinfo": "processor\t: 0\nvendor_id\t: GenuineIntel\ncpu family\t: 6\nmodel\t\t: 142\nmodel name\t: Intel(R) Core(TM) i7\nstepping\t: 10\ncpu MHz\t\t: 2100.000\n\n" +
+			"processor\t: 1\nvendor_id\t: GenuineIntel\ncpu family\t: 6\nmodel\t\t: 142\nmodel name\t: Intel(R) Core(TM) i7\nstepping\t: 10\ncpu MHz\t\t: 3400.000\n",
+	})
+	if err := os.Symlink("../../devices/virtual/net/br0", filepath.Join(hostRoot, "sys/class/net/br0")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		provider HWIDProvider
+		want     string
+	}{
+		{"machine-id", MachineID, "0123456789abcdef"},
+		{"product uuid", ProductUUID, "4C4C4544-0042"},
+		{"mac", MACAddresses, "aa:bb:cc:00:00:01,aa:bb:cc:00:00:02"},
+		{"disk", DiskSerials, "S1,eui.0025"},
+		{"cpu", CPUInfo, "GenuineIntel|Intel(R) Core(TM) i7|6|142|10|2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := tt.provider.HWID(); got != tt.want || err != nil {
+				t.Errorf("HWID() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinuxProvidersMissing(t *testing.T) {
+	fakeHost(t, map[string]string{
+		"etc/machine-id":       "\n",
+		"sys/block/loop0/size": "0\n",
+	})
+	for _, p := range []HWIDProvider{MachineID, ProductUUID, DiskSerials} {
+		if got, err := p.HWID(); err == nil {
+			t.Errorf("HWID() = %q, want an error", got)
+		}
+	}
+	if _, err := DiskSerials.HWID(); !errors.Is(err, ErrHWIDUnavailable) {
+		t.Errorf("DiskSerials error = %v, want %v", err, ErrHWIDUnavailable)
+	}
+}
+
+func TestParseWhoami(t *testing.T) {
+	sid, err := parseWhoami([]byte("\"desktop-1\\alice\",\"S-1-5-21-1004336348-1177238915-682003330-1001\"\r\n"))
+	if sid != "S-1-5-21-1004336348-1177238915-682003330-1001" || err != nil {
+		t.Errorf("parseWhoami() = %q, %v", sid, err)
+	}
+	if _, err := parseWhoami([]byte("ERROR: Access denied\r\n")); err == nil {
+		t.Error("parseWhoami() of an error message succeeded")
+	}
+}
+
+func TestParseIoreg(t *testing.T) {
+	out := []byte(`+-o J314sAP  <class IOPlatformExpertDevice, id 0x100000240, registered, matched, active, busy 0 (0 ms), retain 36>
+    {
+      "IOPlatformUUID" = "9E2BA6A1-7F3E-5C2B-9B1A-1E2F3A4B5C6D"
+      "IOPlatformSerialNumber" = "C02XL0GZJGH5"
+      "manufacturer" = <"Apple Inc.">
+    }
+`)
+	if serial, err := parseIoreg(out, "IOPlatformSerialNumber"); serial != "C02XL0GZJGH5" || err != nil {
+		t.Errorf("parseIoreg() = %q, %v", serial, err)
+	}
+	if _, err := parseIoreg(out, "missing"); !errors.Is(err, ErrHWIDUnavailable) {
+		t.Errorf("parseIoreg() error = %v, want %v", err, ErrHWIDUnavailable)
+	}
+}
+
+func TestCompositeHWID(t *testing.T) {
+	values := map[string]string{"a": "1", "b": "2", "c": "3"}
+	component := func(name string) HWIDComponent {
+		return HWIDComponent{name, HWIDFunc(func() (string, error) {
+			if values[name] == "" {
+				return "", ErrHWIDUnavailable
+			}
+			return values[name], nil
+		})}
+	}
+	newProvider := func(baseline string) *CompositeHWID {
+		return &CompositeHWID{
+			Components:   []HWIDComponent{component("a"), component("b"), component("c")},
+			Tolerance:    1,
+			BaselinePath: baseline,
+		}
+	}
+	baseline := filepath.Join(t.TempDir(), "hwid.dat")
+	p := newProvider(baseline)
+
+	first, err := p.HWID()
+	if err != nil || len(first) != 64 {
+		t.Fatalf("HWID() = %q, %v", first, err)
+	}
+
+	values["b"] = "changed"
+	if got, _ := p.HWID(); got != first {
+		t.Errorf("HWID() with one component changed = %q, want %q", got, first)
+	}
+	// The tolerated change was taken into the baseline, so another one
+	// later is again only one difference.
+	values["c"] = "changed"
+	if got, _ := p.HWID(); got != first {
+		t.Errorf("HWID() after a second change on its own = %q, want %q", got, first)
+	}
+	if got, _ := newProvider(baseline).HWID(); got != first {
+		t.Errorf("HWID() from saved baseline after two changes = %q, want %q", got, first)
+	}
+
+	values["a"], values["b"] = "changed", "changed again"
+	second, _ := p.HWID()
+	if second == first {
+		t.Error("HWID() unchanged with two components changed at once")
+	}
+
+	// The new baseline was saved and is picked up by a fresh provider.
+	values["c"] = "changed again"
+	if got, _ := newProvider(baseline).HWID(); got != second {
+		t.Errorf("HWID() from saved baseline = %q, want %q", got, second)
+	}
+
+	values["a"], values["b"], values["c"] = "", "", ""
+	if _, err := p.HWID(); !errors.Is(err, ErrHWIDUnavailable) {
+		t.Errorf("HWID() with nothing readable error = %v, want %v", err, ErrHWIDUnavailable)
+	}
+}
+
+func TestCompositeHWIDForgedBaseline(t *testing.T) {
+	p := &CompositeHWID{
+		Components:   []HWIDComponent{{"a", HWIDFunc(func() (string, error) { return "1", nil })}},
+		Tolerance:    1,
+		BaselinePath: filepath.Join(t.TempDir(), "hwid.dat"),
+	}
+	want, err := p.HWID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A baseline whose ID was swapped for another machine's, even one
+	// sealed with the right key, or one written in the clear, isn't used.
+	forged := &CompositeHWID{BaselinePath: p.BaselinePath}
+	forged.baseline = &hwidBaseline{ID: strings.Repeat("0", 64), Origin: p.baseline.Origin, Components: p.baseline.Components}
+	if err := forged.saveBaseline(); err != nil {
+		t.Fatal(err)
+	}
+	p.baseline = nil
+	if got, _ := p.HWID(); got != want {
+		t.Errorf("HWID() with forged baseline = %q, want %q", got, want)
+	}
+
+	plain, _ := json.Marshal(hwidBaseline{ID: strings.Repeat("0", 64), Origin: p.baseline.Origin, Components: p.baseline.Components})
+	if err := os.WriteFile(p.BaselinePath, plain, 0600); err != nil {
+		t.Fatal(err)
+	}
+	p.baseline = nil
+	if got, _ := p.HWID(); got != want {
+		t.Errorf("HWID() with plaintext baseline = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,108 @@
+package EpicAuth
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultEnvironment is the environment whose pinned keys a Client uses
+// unless told otherwise. Until keys are pinned for it, it trusts PublicKey.
+const DefaultEnvironment = "production"
+
+// TrustedKey is an ed25519 key responses may be signed with. A zero
+// NotBefore or NotAfter leaves that end of the validity window open.
+type TrustedKey struct {
+	ID        string
+	Key       ed25519.PublicKey
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// ParseTrustedKey builds a TrustedKey from a hex encoded public key.
+func ParseTrustedKey(id, hexKey string) (TrustedKey, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return TrustedKey{}, fmt.Errorf("EpicAuth: key %s: %w", id, err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return TrustedKey{}, fmt.Errorf("EpicAuth: key %s: want %d bytes, got %d", id, ed25519.PublicKeySize, len(key))
+	}
+	return TrustedKey{ID: id, Key: key}, nil
+}
+
+// validAt reports whether t falls in the key's validity window.
+func (k TrustedKey) validAt(t time.Time) bool {
+	if !k.NotBefore.IsZero() && t.Before(k.NotBefore) {
+		return false
+	}
+	if !k.NotAfter.IsZero() && t.After(k.NotAfter) {
+		return false
+	}
+	return true
+}
+
+// KeySet is an ordered list of trusted keys.
+type KeySet []TrustedKey
+
+// Verify tries each key that was valid at the signed timestamp in order
+// and returns the ID of the first one that verifies the signature.
+func (ks KeySet) Verify(body []byte, signature, timestamp string) (string, bool) {
+	signatureBytes, err := hex.DecodeString(signature)
+	if err != nil {
+		return "", false
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", false
+	}
+	signedAt := time.Unix(unix, 0)
+	message := append([]byte(timestamp), body...)
+
+	for _, key := range ks {
+		if len(key.Key) != ed25519.PublicKeySize || !key.validAt(signedAt) {
+			continue
+		}
+		if ed25519.Verify(key.Key, message, signatureBytes) {
+			return key.ID, true
+		}
+	}
+	return "", false
+}
+
+var (
+	pinsMu sync.RWMutex
+	pins   = map[string]KeySet{}
+)
+
+// PinKeys sets the keys trusted by clients created for environment,
+// replacing any keys pinned before. It does not affect existing clients.
+func PinKeys(environment string, keys ...TrustedKey) {
+	pinsMu.Lock()
+	defer pinsMu.Unlock()
+	pins[environment] = append(KeySet(nil), keys...)
+}
+
+// PinnedKeys returns the keys pinned for environment.
+func PinnedKeys(environment string) KeySet {
+	pinsMu.RLock()
+	keys, ok := pins[environment]
+	pinsMu.RUnlock()
+	if !ok && environment == DefaultEnvironment {
+		return publicKeySet("default", PublicKey)
+	}
+	return append(KeySet(nil), keys...)
+}
+
+// publicKeySet returns a set holding the single hex key, or an empty set
+// if it does not parse.
+func publicKeySet(id, hexKey string) KeySet {
+	key, err := ParseTrustedKey(id, hexKey)
+	if err != nil {
+		return nil
+	}
+	return KeySet{key}
+}
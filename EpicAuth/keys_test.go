@@ -0,0 +1,92 @@
+package EpicAuth
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestKeySetVerify(t *testing.T) {
+	oldPub, oldPriv, _ := ed25519.GenerateKey(nil)
+	newPub, newPriv, _ := ed25519.GenerateKey(nil)
+	rotation := time.Unix(1700000000, 0)
+
+	keys := KeySet{
+		{ID: "old", Key: oldPub, NotAfter: rotation},
+		{ID: "new", Key: newPub, NotBefore: rotation.Add(-time.Hour)},
+	}
+	body := []byte(`{"success":true}`)
+	sign := func(priv ed25519.PrivateKey, at time.Time) (string, string) {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		return hex.EncodeToString(ed25519.Sign(priv, append([]byte(timestamp), body...))), timestamp
+	}
+
+	tests := []struct {
+		name   string
+		priv   ed25519.PrivateKey
+		at     time.Time
+		wantID string
+	}{
+		{"old key before rotation", oldPriv, rotation.Add(-2 * time.Hour), "old"},
+		{"new key during overlap", newPriv, rotation.Add(-time.Minute), "new"},
+		{"new key after rotation", newPriv, rotation.Add(time.Hour), "new"},
+		{"old key after it expired", oldPriv, rotation.Add(time.Second), ""},
+		{"new key before it was valid", newPriv, rotation.Add(-2 * time.Hour), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signature, timestamp := sign(tt.priv, tt.at)
+			id, ok := keys.Verify(body, signature, timestamp)
+			if id != tt.wantID || ok != (tt.wantID != "") {
+				t.Errorf("Verify() = %q, %v, want %q", id, ok, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestParseTrustedKey(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(nil)
+
+	if _, err := ParseTrustedKey("k", hex.EncodeToString(pub)); err != nil {
+		t.Errorf("ParseTrustedKey() error = %v", err)
+	}
+	for _, bad := range []string{"", "zz", hex.EncodeToString(pub[:31])} {
+		if _, err := ParseTrustedKey("k", bad); err == nil {
+			t.Errorf("ParseTrustedKey(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestPinnedKeys(t *testing.T) {
+	if got := PinnedKeys(DefaultEnvironment); len(got) != 1 || hex.EncodeToString(got[0].Key) != PublicKey {
+		t.Errorf("PinnedKeys(%q) = %v, want the built-in key", DefaultEnvironment, got)
+	}
+
+	pub, _, _ := ed25519.GenerateKey(nil)
+	PinKeys("staging", TrustedKey{ID: "staging-1", Key: pub})
+	defer PinKeys("staging")
+
+	c := NewClient("test", "abcdefghij", "1.0", WithEnvironment("staging"), WithLogger(nil))
+	if len(c.keys) != 1 || c.keys[0].ID != "staging-1" {
+		t.Errorf("client keys = %v", c.keys)
+	}
+	if got := PinnedKeys("unknown"); len(got) != 0 {
+		t.Errorf("PinnedKeys(unknown) = %v", got)
+	}
+
+	// The package-level functions have to honour pins too.
+	PinKeys(DefaultEnvironment, TrustedKey{ID: "rotated", Key: pub})
+	saved := std
+	std = nil
+	defer func() {
+		std = saved
+		pinsMu.Lock()
+		delete(pins, DefaultEnvironment)
+		pinsMu.Unlock()
+	}()
+	if keys := Default().keys; len(keys) != 1 || keys[0].ID != "rotated" {
+		t.Errorf("default client keys = %v, want the pinned key", keys)
+	}
+}
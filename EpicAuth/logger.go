@@ -0,0 +1,192 @@
+package EpicAuth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SignatureResult is the outcome of checking a response's signature.
+type SignatureResult string
+
+const (
+	SignatureNotChecked    SignatureResult = ""
+	SignatureValid         SignatureResult = "valid"
+	SignatureMissing       SignatureResult = "missing"
+	SignatureInvalid       SignatureResult = "invalid"
+	SignatureClockSkew     SignatureResult = "clock_skew"
+	SignatureNonceMismatch SignatureResult = "nonce_mismatch"
+)
+
+// RequestEvent describes one API request. Secrets in Request and Response
+// are redacted.
+type RequestEvent struct {
+	Type       string
+	Time       time.Time
+	Latency    time.Duration
+	StatusCode int // 0 if no response arrived
+	Signature  SignatureResult
+	KeyID      string // the trusted key that verified the response
+	Err        error
+	Request    map[string]string
+	Response   string
+}
+
+// Logger receives an event for every request a client sends. It must be
+// safe for concurrent use.
+type Logger interface {
+	LogRequest(event RequestEvent)
+}
+
+// LoggerFunc adapts a function to Logger.
+type LoggerFunc func(event RequestEvent)
+
+func (f LoggerFunc) LogRequest(event RequestEvent) {
+	f(event)
+}
+
+// NewSlogLogger logs events to l, at debug level for successful requests
+// and warn level for failed ones.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return LoggerFunc(func(event RequestEvent) {
+		level := slog.LevelDebug
+		attrs := []slog.Attr{
+			slog.String("type", event.Type),
+			slog.Duration("latency", event.Latency),
+			slog.Int("status", event.StatusCode),
+			slog.String("signature", string(event.Signature)),
+		}
+		if event.KeyID != "" {
+			attrs = append(attrs, slog.String("key_id", event.KeyID))
+		}
+		if event.Err != nil {
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.String("error", event.Err.Error()))
+		}
+		attrs = append(attrs, slog.Any("request", event.Request), slog.String("response", event.Response))
+		l.LogAttrs(context.Background(), level, "EpicAuth request", attrs...)
+	})
+}
+
+// FileLogger writes events as JSON lines to a file, rotating it once it
+// grows past MaxSize. Write errors are ignored.
+type FileLogger struct {
+	Path string
+	// MaxSize is the size in bytes at which the file is rotated.
+	MaxSize int64
+	// MaxBackups is how many rotated files, Path.1 to Path.N, are kept.
+	MaxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewFileLogger returns a FileLogger for path that rotates at 5 MiB and
+// keeps 3 old files. The file is created on the first event.
+func NewFileLogger(path string) *FileLogger {
+	return &FileLogger{Path: path, MaxSize: 5 << 20, MaxBackups: 3}
+}
+
+// DefaultLogPath returns where clients log by default: a file named after
+// the executable in the user's cache directory.
+func DefaultLogPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "EpicAuth", "Debug", filepath.Base(os.Args[0]), "log.txt"), nil
+}
+
+type fileLogEntry struct {
+	Time      time.Time         `json:"time"`
+	Type      string            `json:"type"`
+	LatencyMS int64             `json:"latency_ms"`
+	Status    int               `json:"status,omitempty"`
+	Signature SignatureResult   `json:"signature,omitempty"`
+	KeyID     string            `json:"key_id,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	Request   map[string]string `json:"request,omitempty"`
+	Response  string            `json:"response,omitempty"`
+}
+
+func (l *FileLogger) LogRequest(event RequestEvent) {
+	entry := fileLogEntry{
+		Time:      event.Time,
+		Type:      event.Type,
+		LatencyMS: event.Latency.Milliseconds(),
+		Status:    event.StatusCode,
+		Signature: event.Signature,
+		KeyID:     event.KeyID,
+		Request:   event.Request,
+		Response:  event.Response,
+	}
+	if event.Err != nil {
+		entry.Error = event.Err.Error()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil && l.MaxSize > 0 && l.size+int64(len(line)) > l.MaxSize {
+		l.rotate()
+	}
+	if l.file == nil && l.open() != nil {
+		return
+	}
+	n, _ := l.file.Write(line)
+	l.size += int64(n)
+}
+
+// Close closes the log file. Later events reopen it.
+func (l *FileLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func (l *FileLogger) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(l.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file, l.size = file, info.Size()
+	return nil
+}
+
+// rotate moves Path to Path.1, Path.1 to Path.2 and so on, dropping the
+// oldest file.
+func (l *FileLogger) rotate() {
+	l.file.Close()
+	l.file = nil
+	for i := l.MaxBackups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", l.Path, i), fmt.Sprintf("%s.%d", l.Path, i+1))
+	}
+	if l.MaxBackups > 0 {
+		os.Rename(l.Path, l.Path+".1")
+	} else {
+		os.Remove(l.Path)
+	}
+}
@@ -0,0 +1,129 @@
+package EpicAuth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestEvents(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	_, otherPriv, _ := ed25519.GenerateKey(nil)
+	key, _ := ParseTrustedKey("2024", hex.EncodeToString(pub))
+
+	tests := []struct {
+		name      string
+		transport Transport
+		want      RequestEvent
+	}{
+		{"valid", signedTransport(priv, time.Now(), 0, `{"success":true,"sessionid":"abc"}`),
+			RequestEvent{StatusCode: 200, Signature: SignatureValid, KeyID: "2024", Response: `{"sessionid":"REDACTED","success":true}`}},
+		{"invalid", signedTransport(otherPriv, time.Now(), 0, `{"success":true}`),
+			RequestEvent{StatusCode: 200, Signature: SignatureInvalid, Err: ErrSignatureInvalid, Response: `{"success":true}`}},
+		{"skewed", signedTransport(priv, time.Now(), time.Hour, `{"success":true}`),
+			RequestEvent{StatusCode: 200, Signature: SignatureClockSkew, KeyID: "2024", Err: ErrClockSkew, Response: `{"success":true}`}},
+		{"unsigned", TransportFunc(func(context.Context, url.Values) (*RawResponse, error) {
+			return &RawResponse{StatusCode: 200, Body: []byte("{}")}, nil
+		}), RequestEvent{StatusCode: 200, Signature: SignatureMissing, Err: ErrSignatureInvalid, Response: "{}"}},
+		{"network", TransportFunc(func(context.Context, url.Values) (*RawResponse, error) {
+			return nil, io.EOF
+		}), RequestEvent{Signature: SignatureNotChecked, Err: io.EOF}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []RequestEvent
+			c := NewClient("test", "abcdefghij", "1.0",
+				WithTrustedKeys(key),
+				WithTransport(tt.transport),
+				WithRetryPolicy(RetryPolicy{}),
+				WithLogger(LoggerFunc(func(event RequestEvent) { events = append(events, event) })),
+			)
+			c.doRequest(context.Background(), map[string]string{"type": "login", "username": "alice", "pass": "hunter2"})
+
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1", len(events))
+			}
+			got := events[0]
+			if got.Type != "login" || got.Time.IsZero() || got.Latency <= 0 {
+				t.Errorf("event = %+v", got)
+			}
+			if got.Request["username"] != "alice" || got.Request["pass"] != "REDACTED" {
+				t.Errorf("event request = %v", got.Request)
+			}
+			got.Response = strings.Replace(got.Response, `"nonce":"`+got.Request["nonce"]+`",`, "", 1)
+			if got.StatusCode != tt.want.StatusCode || got.Signature != tt.want.Signature || got.KeyID != tt.want.KeyID ||
+				!errors.Is(got.Err, tt.want.Err) || got.Response != tt.want.Response {
+				t.Errorf("event = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "log.txt")
+	l := NewFileLogger(path)
+	l.MaxSize = 300
+	l.MaxBackups = 2
+	defer l.Close()
+
+	for i := 0; i < 10; i++ {
+		l.LogRequest(RequestEvent{Type: "check", Time: time.Now(), Signature: SignatureValid, Err: ErrSessionExpired})
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				t.Errorf("%s: bad line %q: %v", name, scanner.Text(), err)
+			}
+			if entry["type"] != "check" || entry["error"] != ErrSessionExpired.Error() {
+				t.Errorf("%s: entry = %v", name, entry)
+			}
+		}
+		f.Close()
+		if info, _ := os.Stat(name); info.Size() > l.MaxSize {
+			t.Errorf("%s is %d bytes, want at most %d", name, info.Size(), l.MaxSize)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("%s.3 exists, want at most 2 backups", path)
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	l.LogRequest(RequestEvent{Type: "init", StatusCode: 200, Signature: SignatureValid, KeyID: "2024"})
+	l.LogRequest(RequestEvent{Type: "check", Err: io.EOF})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	var first, second map[string]interface{}
+	json.Unmarshal([]byte(lines[0]), &first)
+	json.Unmarshal([]byte(lines[1]), &second)
+	if first["level"] != "DEBUG" || first["type"] != "init" || first["signature"] != "valid" || first["key_id"] != "2024" {
+		t.Errorf("first record = %v", first)
+	}
+	if second["level"] != "WARN" || second["error"] != "EOF" {
+		t.Errorf("second record = %v", second)
+	}
+}
@@ -0,0 +1,379 @@
+package EpicAuth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// OfflineConfig configures WithOfflineCache.
+type OfflineConfig struct {
+	// Grace is how long after the cached login was signed by the server it
+	// stays usable. It never extends past the user's last subscription
+	// expiry. Zero disables the cache.
+	Grace time.Duration
+	// Path is the cache file. Defaults to a file named after the
+	// application in the user's cache directory.
+	Path string
+	// Key encrypts the cache file. Defaults to a key derived from the
+	// application and the HWID, which ties the file to this machine.
+	Key []byte
+}
+
+func (cfg OfflineConfig) enabled() bool {
+	return cfg.Grace > 0
+}
+
+// offlineEntry is the cached login. Body, Signature and Timestamp are
+// exactly what the server sent, so the entry is verified again on every
+// use.
+type offlineEntry struct {
+	Type       string `json:"type"`
+	Salt       []byte `json:"salt"`
+	Credential []byte `json:"credential"`
+	Body       []byte `json:"body"`
+	Signature  string `json:"signature"`
+	Timestamp  string `json:"timestamp"`
+}
+
+// errNoOfflineLogin means there is no cached login for the credentials.
+var errNoOfflineLogin = errors.New("EpicAuth: no offline login cached")
+
+// Offline reports whether the client is running from the offline cache.
+// Every call tries to reach the server again and leaves offline mode once
+// it can. Until then only Login and License work; other calls fail with
+// ErrOffline, and Check with ErrGraceExpired once the grace period is
+// over.
+func (c *Client) Offline() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.offlineMode
+}
+
+// OfflineGrace returns how much longer the cached login can be used.
+// ok is false if there is no usable cached login.
+func (c *Client) OfflineGrace() (remaining time.Duration, ok bool) {
+	entry, err := c.loadOffline()
+	if err != nil {
+		return 0, false
+	}
+	_, deadline, err := c.verifyOffline(entry)
+	if err != nil {
+		return 0, false
+	}
+	return deadline.Sub(c.serverNow()), true
+}
+
+// ClearOfflineCache removes the cached login, if any.
+func (c *Client) ClearOfflineCache() error {
+	path, err := c.offlinePath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) goOffline() {
+	c.mu.Lock()
+	c.offlineMode = true
+	c.initialized = true
+	c.mu.Unlock()
+}
+
+// reconnect starts a session for a client running offline, once the
+// server can be reached again, and logs it in with the credentials of the
+// cached login, if one was used.
+func (c *Client) reconnect(ctx context.Context) error {
+	var token []byte
+	if c.token != nil {
+		var err error
+		if token, err = c.readToken(); err != nil {
+			return err
+		}
+	}
+	sessionID, err := c.initSession(ctx, token)
+	if err != nil {
+		if unreachable(err) {
+			return fmt.Errorf("%w: %v", ErrOffline, err)
+		}
+		return err
+	}
+
+	c.mu.Lock()
+	if !c.offlineMode {
+		// Another call reconnected first.
+		c.mu.Unlock()
+		return nil
+	}
+	c.sessionID = sessionID
+	c.tokenHash = ""
+	if token != nil {
+		c.tokenHash = tokenHash(token)
+	}
+	c.offlineMode = false
+	login := c.offlineLogin
+	c.offlineLogin = nil
+	c.mu.Unlock()
+
+	if login != nil {
+		login["sessionid"] = sessionID
+		if _, err := c.login(ctx, login); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loginOffline logs in from the cache, without changing whether the
+// client is offline. It returns errNoOfflineLogin if nothing is cached
+// for the credentials in postData.
+func (c *Client) loginOffline(postData map[string]string) (*LoginResponse, error) {
+	entry, err := c.loadOffline()
+	if err != nil || !hmac.Equal(entry.Credential, credential(c.name, c.ownerID, postData, entry.Salt)) {
+		return nil, errNoOfflineLogin
+	}
+	response, _, err := c.verifyOffline(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.user = *response.Info
+	if c.offlineMode {
+		c.offlineLogin = make(map[string]string, len(postData))
+		for key, value := range postData {
+			c.offlineLogin[key] = value
+		}
+	}
+	c.mu.Unlock()
+	return response, nil
+}
+
+// verifyOffline checks the entry's signature and returns the login it
+// holds along with the end of its grace period.
+func (c *Client) verifyOffline(entry *offlineEntry) (*LoginResponse, time.Time, error) {
+	if _, ok := c.keys.Verify(entry.Body, entry.Signature, entry.Timestamp); !ok {
+		return nil, time.Time{}, ErrSignatureInvalid
+	}
+	signed, err := strconv.ParseInt(entry.Timestamp, 10, 64)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: invalid timestamp format: %v", ErrSignatureInvalid, err)
+	}
+	response, err := decodeLogin(entry.Type, entry.Body)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	signedAt := time.Unix(signed, 0)
+	deadline := signedAt.Add(c.offline.Grace)
+	var lastExpiry time.Time
+	for _, sub := range response.Info.Subscriptions {
+		if expiresAt, err := sub.ExpiresAt(); err == nil && expiresAt.After(lastExpiry) {
+			lastExpiry = expiresAt
+		}
+	}
+	if !lastExpiry.IsZero() && lastExpiry.Before(deadline) {
+		deadline = lastExpiry
+	}
+
+	now := c.serverNow()
+	// A clock set before the server's signature means it was turned back.
+	if now.Before(signedAt.Add(-c.skewTolerance)) {
+		return nil, time.Time{}, ErrClockSkew
+	}
+	if !now.Before(deadline) {
+		return nil, time.Time{}, ErrGraceExpired
+	}
+	return response, deadline, nil
+}
+
+// credentialIterations is the PBKDF2 work factor for cached credentials.
+const credentialIterations = 100000
+
+// credential identifies the user logging in with postData without
+// storing the password or license key itself. It is a salted PBKDF2 hash,
+// so a copy of the cache file doesn't make guessing the password cheap.
+func credential(name, ownerID string, postData map[string]string, salt []byte) []byte {
+	secret := "user\x00" + postData["username"] + "\x00" + postData["pass"]
+	if postData["type"] == "license" {
+		secret = "license\x00" + postData["key"]
+	}
+	return pbkdf2(sha256.New, []byte(name+"\x00"+ownerID+"\x00"+secret), salt, credentialIterations, sha256.Size)
+}
+
+// pbkdf2 derives a key of keyLen bytes from password as in RFC 8018.
+func pbkdf2(h func() hash.Hash, password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(h, password)
+	var key, u []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write([]byte{byte(block >> 24), byte(block >> 16), byte(block >> 8), byte(block)})
+		u = prf.Sum(u[:0])
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+func (c *Client) saveOffline(postData map[string]string, raw *RawResponse) error {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(offlineEntry{
+		Type:       postData["type"],
+		Salt:       salt,
+		Credential: credential(c.name, c.ownerID, postData, salt),
+		Body:       raw.Body,
+		Signature:  raw.Signature,
+		Timestamp:  raw.Timestamp,
+	})
+	if err != nil {
+		return err
+	}
+	aead, err := c.localCipher("offline", c.offline.Key)
+	if err != nil {
+		return err
+	}
+	data, err := seal(aead, plaintext, []byte(c.name+c.ownerID))
+	if err != nil {
+		return err
+	}
+
+	path, err := c.offlinePath()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+func (c *Client) loadOffline() (*offlineEntry, error) {
+	path, err := c.offlinePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := c.localCipher("offline", c.offline.Key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := unseal(aead, data, []byte(c.name+c.ownerID))
+	if err != nil {
+		return nil, err
+	}
+
+	var entry offlineEntry
+	if err := json.Unmarshal(plaintext, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// localCipher returns the cipher for data the client keeps on disk. Unless
+// key is set, the key is derived from the application and the HWID, so
+// the data can't be read on another machine.
+func (c *Client) localCipher(purpose string, key []byte) (cipher.AEAD, error) {
+	if key == nil {
+		hwid, err := c.HWID()
+		if err != nil {
+			return nil, err
+		}
+		key = []byte("EpicAuth " + purpose + "\x00" + c.name + "\x00" + c.ownerID + "\x00" + hwid)
+	}
+	return newFileCipher(key)
+}
+
+// newFileCipher returns an AES-GCM cipher keyed with the hash of key.
+func newFileCipher(key []byte) (cipher.AEAD, error) {
+	sum := sha256.Sum256(key)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts data for a file, prefixed with a random nonce.
+func seal(aead cipher.AEAD, data, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, data, additional), nil
+}
+
+// unseal decrypts data written by seal.
+func unseal(aead cipher.AEAD, data, additional []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additional)
+}
+
+func (c *Client) offlinePath() (string, error) {
+	if c.offline.Path != "" {
+		return c.offline.Path, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "EpicAuth", c.name+"-"+c.ownerID+".offline"), nil
+}
+
+// writeFileAtomic replaces path with data, so readers never see a
+// partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// unreachable reports whether err means the server couldn't be reached,
+// as opposed to the server rejecting the request.
+func unreachable(err error) bool {
+	var statusErr *StatusError
+	return IsNetworkError(err) || errors.As(err, &statusErr)
+}
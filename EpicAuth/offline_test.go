@@ -0,0 +1,269 @@
+package EpicAuth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"EpicAuth/EpicAuth/epicauthtest"
+)
+
+// switchTransport fails every request with io.EOF while down is set.
+type switchTransport struct {
+	down bool
+	next Transport
+}
+
+func (t *switchTransport) Send(ctx context.Context, form url.Values) (*RawResponse, error) {
+	if t.down {
+		return nil, io.EOF
+	}
+	return t.next.Send(ctx, form)
+}
+
+func TestOfflineCache(t *testing.T) {
+	s := epicauthtest.NewServer(epicauthtest.App{})
+	defer s.Close()
+	s.AddUser(epicauthtest.User{
+		Username:      "alice",
+		Password:      "hunter2",
+		Subscriptions: []epicauthtest.Subscription{{Name: "default", Key: "K", Expiry: time.Now().Add(time.Hour)}},
+	})
+	s.AddLicense(epicauthtest.License{Key: "LICENSE-1", Subscription: "default", Duration: 48 * time.Hour})
+
+	config := OfflineConfig{
+		Grace: 24 * time.Hour,
+		Path:  filepath.Join(t.TempDir(), "offline"),
+		Key:   []byte("test key"),
+	}
+	newClient := func(transport *switchTransport, config OfflineConfig) *Client {
+		transport.next = &HTTPTransport{URL: s.URL}
+		return NewClient(epicauthtest.DefaultName, epicauthtest.DefaultOwnerID, epicauthtest.DefaultVersion,
+			WithPublicKey(s.PublicKey),
+			WithTransport(transport),
+			WithRetryPolicy(RetryPolicy{}),
+			WithOfflineCache(config),
+			WithHWIDProvider(HWIDFunc(func() (string, error) { return "test-hwid", nil })),
+			WithLogger(nil),
+		)
+	}
+
+	online := &switchTransport{}
+	c := newClient(online, config)
+	if err := c.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if _, ok := c.OfflineGrace(); ok {
+		t.Error("OfflineGrace() ok before any login")
+	}
+	if _, err := c.Login("alice", "wrong"); errors.Is(err, ErrOffline) || err == nil {
+		t.Errorf("Login() with a rejected password error = %v, want the server's error", err)
+	}
+	if _, err := c.Login("alice", "hunter2"); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	// The grace period is cut short by the subscription expiring in an hour.
+	remaining, ok := c.OfflineGrace()
+	if !ok || remaining > time.Hour || remaining < 59*time.Minute {
+		t.Errorf("OfflineGrace() = %v, %v, want about an hour", remaining, ok)
+	}
+
+	t.Run("login falls back", func(t *testing.T) {
+		online.down = true
+		response, err := c.Login("alice", "hunter2")
+		if err != nil || response.Info.Username != "alice" || c.Offline() {
+			t.Errorf("Login() = %+v, %v, offline %v, want the cached login for this call only", response, err, c.Offline())
+		}
+		online.down = false
+		if _, err := c.Check(); err != nil {
+			t.Errorf("Check() once the network is back error = %v", err)
+		}
+	})
+
+	t.Run("network comes back", func(t *testing.T) {
+		transport := &switchTransport{down: true}
+		c := newClient(transport, config)
+		if err := c.Init(); err != nil || !c.Offline() {
+			t.Fatalf("Init() error = %v, offline %v", err, c.Offline())
+		}
+		if _, err := c.Login("alice", "hunter2"); err != nil {
+			t.Fatalf("Login() error = %v", err)
+		}
+		if _, err := c.Check(); !errors.Is(err, ErrOffline) {
+			t.Errorf("Check() while down error = %v, want %v", err, ErrOffline)
+		}
+
+		transport.down = false
+		if _, err := c.Check(); err != nil || c.Offline() {
+			t.Errorf("Check() once the network is back error = %v, offline %v", err, c.Offline())
+		}
+		if _, err := c.Login("alice", "hunter2"); err != nil || c.SessionID() == "" {
+			t.Errorf("Login() online error = %v, session %q", err, c.SessionID())
+		}
+	})
+
+	t.Run("grace runs out", func(t *testing.T) {
+		c := newClient(&switchTransport{down: true}, config)
+		if err := c.Init(); err != nil {
+			t.Fatalf("Init() error = %v", err)
+		}
+		h, err := c.StartHeartbeat(context.Background(), HeartbeatConfig{Interval: 10 * time.Millisecond})
+		if err != nil {
+			t.Fatalf("StartHeartbeat() error = %v", err)
+		}
+		if event := <-h.Events(); event.Kind != HeartbeatError || !errors.Is(event.Err, ErrOffline) {
+			t.Errorf("heartbeat event = %+v, want a transient %v", event, ErrOffline)
+		}
+		h.Stop()
+
+		c.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+		if _, err := c.Check(); !errors.Is(err, ErrGraceExpired) {
+			t.Errorf("Check() error = %v, want %v", err, ErrGraceExpired)
+		}
+		h, _ = c.StartHeartbeat(context.Background(), HeartbeatConfig{Interval: 10 * time.Millisecond})
+		if event := <-h.Events(); event.Kind != HeartbeatSessionInvalid || !errors.Is(event.Err, ErrGraceExpired) {
+			t.Errorf("heartbeat event = %+v, want %v", event, ErrGraceExpired)
+		}
+		<-h.Done()
+	})
+
+	t.Run("init offline", func(t *testing.T) {
+		c := newClient(&switchTransport{down: true}, config)
+		if err := c.Init(); err != nil || !c.Offline() {
+			t.Fatalf("Init() error = %v, offline %v", err, c.Offline())
+		}
+		if _, err := c.Login("alice", "wrong"); !errors.Is(err, ErrOffline) {
+			t.Errorf("Login() with another password error = %v, want %v", err, ErrOffline)
+		}
+		if _, err := c.Login("alice", "hunter2"); err != nil || c.User().Username != "alice" {
+			t.Errorf("Login() error = %v, user %q", err, c.User().Username)
+		}
+		if _, err := c.Var("anything"); !errors.Is(err, ErrOffline) {
+			t.Errorf("Var() error = %v, want %v", err, ErrOffline)
+		}
+	})
+
+	t.Run("grace expired", func(t *testing.T) {
+		c := newClient(&switchTransport{down: true}, config)
+		c.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+		if _, ok := c.OfflineGrace(); ok {
+			t.Error("OfflineGrace() ok after the subscription expired")
+		}
+		if err := c.Init(); !IsNetworkError(err) {
+			t.Errorf("Init() error = %v, want the network error", err)
+		}
+	})
+
+	t.Run("clock turned back", func(t *testing.T) {
+		c := newClient(&switchTransport{down: true}, config)
+		c.now = func() time.Time { return time.Now().Add(-time.Hour) }
+		if _, ok := c.OfflineGrace(); ok {
+			t.Error("OfflineGrace() ok with the clock turned back")
+		}
+	})
+
+	t.Run("other machine", func(t *testing.T) {
+		other := config
+		other.Key = []byte("other key")
+		if _, ok := newClient(&switchTransport{}, other).OfflineGrace(); ok {
+			t.Error("OfflineGrace() ok with another key")
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		data, _ := ioutil.ReadFile(config.Path)
+		tampered := config
+		tampered.Path = filepath.Join(t.TempDir(), "offline")
+		data[len(data)-1] ^= 1
+		ioutil.WriteFile(tampered.Path, data, 0600)
+		if _, ok := newClient(&switchTransport{}, tampered).OfflineGrace(); ok {
+			t.Error("OfflineGrace() ok for a tampered file")
+		}
+	})
+
+	t.Run("license", func(t *testing.T) {
+		c := newClient(online, config)
+		if err := c.Init(); err != nil {
+			t.Fatalf("Init() error = %v", err)
+		}
+		if _, err := c.License("LICENSE-1"); err != nil {
+			t.Fatalf("License() error = %v", err)
+		}
+		remaining, ok := c.OfflineGrace()
+		if !ok || remaining < 23*time.Hour {
+			t.Errorf("OfflineGrace() = %v, %v, want the full grace period", remaining, ok)
+		}
+
+		offline := newClient(&switchTransport{down: true}, config)
+		if err := offline.Init(); err != nil {
+			t.Fatalf("Init() error = %v", err)
+		}
+		if _, err := offline.Login("alice", "hunter2"); !errors.Is(err, ErrOffline) {
+			t.Errorf("Login() error = %v, want %v: the cache only holds the license", err, ErrOffline)
+		}
+		if _, err := offline.License("LICENSE-1"); err != nil {
+			t.Errorf("License() error = %v", err)
+		}
+	})
+
+	if err := c.ClearOfflineCache(); err != nil {
+		t.Fatalf("ClearOfflineCache() error = %v", err)
+	}
+	if _, ok := c.OfflineGrace(); ok {
+		t.Error("OfflineGrace() ok after ClearOfflineCache")
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	// RFC 7914, section 11.
+	got := hex.EncodeToString(pbkdf2(sha256.New, []byte("passwd"), []byte("salt"), 1, 64))
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	if got != want {
+		t.Errorf("pbkdf2() = %s, want %s", got, want)
+	}
+}
+
+func TestWatchTokenOffline(t *testing.T) {
+	s := epicauthtest.NewServer(epicauthtest.App{Token: "tok-1"})
+	defer s.Close()
+	token := filepath.Join(t.TempDir(), "token.txt")
+	if err := ioutil.WriteFile(token, []byte("tok-1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(epicauthtest.DefaultName, epicauthtest.DefaultOwnerID, epicauthtest.DefaultVersion,
+		WithAPIURL(s.URL),
+		WithPublicKey(s.PublicKey),
+		WithTokenPath(token),
+		WithLogger(nil),
+	)
+	// As if Init had found the server down.
+	c.goOffline()
+
+	s.SetToken("tok-2")
+	ioutil.WriteFile(token, []byte("tok-2"), 0600)
+	w, err := c.WatchToken(context.Background(), 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WatchToken() error = %v", err)
+	}
+	defer w.Stop()
+
+	select {
+	case event := <-w.Events():
+		if event.Err != nil {
+			t.Errorf("re-init error = %v", event.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("token change not noticed")
+	}
+	if c.Offline() || c.SessionID() == "" {
+		t.Errorf("client offline %v with session %q after the new token, want online", c.Offline(), c.SessionID())
+	}
+}
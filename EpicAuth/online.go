@@ -0,0 +1,99 @@
+package EpicAuth
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// OnlineEvent is a change in who is online.
+type OnlineEvent struct {
+	Time   time.Time
+	Joined []OnlineUser
+	Left   []OnlineUser
+	// Online is everyone online after the change.
+	Online []OnlineUser
+}
+
+// OnlineWatcher polls the list of online users. Create one with
+// Client.WatchOnline.
+type OnlineWatcher struct {
+	*poller
+	c      *Client
+	events chan OnlineEvent
+
+	online map[string]OnlineUser
+}
+
+// WatchOnline polls the online users every interval until ctx is done,
+// Stop is called or the session turns out invalid. The first event lists
+// everyone online as joined; later events are only sent when someone
+// joins or leaves.
+func (c *Client) WatchOnline(ctx context.Context, interval time.Duration) (*OnlineWatcher, error) {
+	if err := c.CheckInit(); err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	users, err := c.FetchOnlineContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &OnlineWatcher{
+		poller: newPoller(ctx),
+		c:      c,
+		events: make(chan OnlineEvent, 16),
+	}
+	w.events <- w.diff(users)
+	w.start(interval, w.poll, func() { close(w.events) })
+	return w, nil
+}
+
+// Events returns the changes. The channel is closed when the watcher
+// stops.
+func (w *OnlineWatcher) Events() <-chan OnlineEvent {
+	return w.events
+}
+
+func (w *OnlineWatcher) poll(ctx context.Context) error {
+	users, err := w.c.FetchOnlineContext(ctx)
+	if err != nil {
+		return err
+	}
+	event := w.diff(users)
+	if len(event.Joined) == 0 && len(event.Left) == 0 {
+		return nil
+	}
+	select {
+	case w.events <- event:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+// diff compares users to the last poll by credential and remembers them.
+func (w *OnlineWatcher) diff(users []OnlineUser) OnlineEvent {
+	event := OnlineEvent{Time: w.c.now()}
+	online := make(map[string]OnlineUser, len(users))
+	for _, user := range users {
+		if _, dup := online[user.Credential]; dup {
+			continue
+		}
+		online[user.Credential] = user
+		event.Online = append(event.Online, user)
+		if _, ok := w.online[user.Credential]; !ok {
+			event.Joined = append(event.Joined, user)
+		}
+	}
+	for credential, user := range w.online {
+		if _, ok := online[credential]; !ok {
+			event.Left = append(event.Left, user)
+		}
+	}
+	sort.Slice(event.Left, func(i, j int) bool { return event.Left[i].Credential < event.Left[j].Credential })
+	w.online = online
+	return event
+}
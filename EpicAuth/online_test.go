@@ -0,0 +1,83 @@
+package EpicAuth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	EpicAuth "EpicAuth/EpicAuth"
+	"EpicAuth/EpicAuth/epicauthtest"
+)
+
+func TestFetchOnlineEmpty(t *testing.T) {
+	s := newServer(t)
+	c := initClient(t, s)
+
+	online, err := c.FetchOnline()
+	if err != nil || online == nil || len(online) != 0 {
+		t.Errorf("FetchOnline() with nobody online = %v, %v, want an empty list", online, err)
+	}
+}
+
+func TestWatchOnline(t *testing.T) {
+	s := newServer(t)
+	s.AddUser(epicauthtest.User{
+		Username:      "bob",
+		Password:      "pw",
+		Subscriptions: []epicauthtest.Subscription{{Name: "default", Key: "K", Expiry: time.Now().Add(time.Hour)}},
+	})
+	alice := loggedIn(t, s)
+
+	w, err := alice.WatchOnline(context.Background(), 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WatchOnline() error = %v", err)
+	}
+	defer w.Stop()
+
+	next := func() EpicAuth.OnlineEvent {
+		t.Helper()
+		select {
+		case event := <-w.Events():
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+			return EpicAuth.OnlineEvent{}
+		}
+	}
+	credentials := func(users []EpicAuth.OnlineUser) (names []string) {
+		for _, user := range users {
+			names = append(names, user.Credential)
+		}
+		return names
+	}
+
+	if event := next(); len(event.Joined) != 1 || event.Joined[0].Credential != "alice" || len(event.Left) != 0 {
+		t.Errorf("first event joined %v, left %v, want alice joined", credentials(event.Joined), credentials(event.Left))
+	}
+
+	bob := initClient(t, s)
+	if _, err := bob.Login("bob", "pw"); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if event := next(); len(event.Joined) != 1 || event.Joined[0].Credential != "bob" || len(event.Online) != 2 {
+		t.Errorf("event joined %v, online %v, want bob joined", credentials(event.Joined), credentials(event.Online))
+	}
+
+	if err := bob.Logout(); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if event := next(); len(event.Left) != 1 || event.Left[0].Credential != "bob" || len(event.Joined) != 0 {
+		t.Errorf("event joined %v, left %v, want bob left", credentials(event.Joined), credentials(event.Left))
+	}
+
+	s.ExpireSessions()
+	select {
+	case <-w.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher still running after the session expired")
+	}
+	if err := w.Err(); !errors.Is(err, EpicAuth.ErrSessionExpired) {
+		t.Errorf("Err() = %v, want %v", err, EpicAuth.ErrSessionExpired)
+	}
+}
@@ -0,0 +1,89 @@
+package EpicAuth
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// poller runs the loop behind the background workers: Heartbeat,
+// ChatSubscription, OnlineWatcher, StatsRefresher and TokenWatcher. Each
+// embeds one, so they stop for the same reasons.
+type poller struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// newPoller returns a poller that stops when ctx is done. It doesn't poll
+// until start is called.
+func newPoller(ctx context.Context) *poller {
+	ctx, cancel := context.WithCancel(ctx)
+	return &poller{ctx: ctx, cancel: cancel, done: make(chan struct{})}
+}
+
+// start calls poll every interval until the poller's context is done,
+// Stop is called or poll returns an error that ends the session. Other
+// errors are transient, e.g. the network, and poll runs again on the next
+// tick. stopped, if not nil, runs once polling has stopped, before Done
+// is closed.
+func (p *poller) start(interval time.Duration, poll func(ctx context.Context) error, stopped func()) {
+	go func() {
+		defer close(p.done)
+		if stopped != nil {
+			defer stopped()
+		}
+		defer p.cancel()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			err := poll(p.ctx)
+			if p.ctx.Err() != nil {
+				return
+			}
+			if sessionEnded(err) {
+				p.err = err
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops polling and waits for the poller to finish.
+func (p *poller) Stop() {
+	p.cancel()
+	<-p.done
+}
+
+// Done is closed when polling has stopped.
+func (p *poller) Done() <-chan struct{} {
+	return p.done
+}
+
+// Err returns the error that stopped polling once Done is closed. It is
+// nil if polling was stopped through ctx or Stop.
+func (p *poller) Err() error {
+	select {
+	case <-p.done:
+		return p.err
+	default:
+		return nil
+	}
+}
+
+// sessionEnded reports whether err means the session is gone for good,
+// so polling again is pointless: the server rejected the request, the
+// client isn't initialized, or the offline grace period ran out.
+func sessionEnded(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) || errors.Is(err, ErrNotInitialized) || errors.Is(err, ErrGraceExpired)
+}
@@ -0,0 +1,42 @@
+package EpicAuth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestPoller(t *testing.T) {
+	stopErr := &APIError{Type: "check", Message: "Session not found.", Err: ErrSessionExpired}
+	errs := []error{nil, io.EOF, errors.New("boom"), stopErr, nil}
+
+	calls := 0
+	stopped := false
+	p := newPoller(context.Background())
+	p.start(time.Millisecond, func(context.Context) error {
+		err := errs[calls]
+		calls++
+		return err
+	}, func() { stopped = true })
+
+	select {
+	case <-p.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("poller didn't stop on a session error")
+	}
+	if calls != 4 || !stopped {
+		t.Errorf("poller stopped after %d calls, stopped callback run %v, want 4 and true", calls, stopped)
+	}
+	if !errors.Is(p.Err(), ErrSessionExpired) {
+		t.Errorf("Err() = %v, want %v", p.Err(), ErrSessionExpired)
+	}
+
+	p = newPoller(context.Background())
+	p.start(time.Millisecond, func(context.Context) error { return io.EOF }, nil)
+	p.Stop()
+	if err := p.Err(); err != nil {
+		t.Errorf("Err() after Stop = %v, want nil", err)
+	}
+}
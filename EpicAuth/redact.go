@@ -0,0 +1,165 @@
+package EpicAuth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DefaultRedactionRules are the rules clients redact logs with unless
+// told otherwise.
+var DefaultRedactionRules = []string{
+	"**.sessionid", "**.ownerid", "**.secret",
+	"**.pass", "**.key", "**.token", "**.thash",
+	"**.hwid", "**.ip", "**.email", "**.contents",
+	"app", "version", "fileid", "webhooks",
+}
+
+// requestRedactionRules and responseRedactionRules are added to a
+// client's rules for request types whose generic fields hold secrets,
+// such as variables.
+var (
+	requestRedactionRules = map[string][]string{
+		"setvar": {"data"},
+	}
+	responseRedactionRules = map[string][]string{
+		"var":    {"message"},
+		"getvar": {"response"},
+	}
+)
+
+// maxLoggedResponse is how much of a response body is logged.
+const maxLoggedResponse = 16 << 10
+
+// Redactor replaces sensitive values in requests and responses before
+// they are logged.
+//
+// A rule is a dotted path of keys such as "info.hwid". "*" matches any
+// one key and "**" any number of keys, so "**.hwid" matches hwid at any
+// depth. Arrays don't add to the path: "info.subscriptions.key" matches
+// the key of every subscription. Keys match case-insensitively.
+type Redactor struct {
+	rules [][]string
+}
+
+// NewRedactor returns a Redactor for rules.
+func NewRedactor(rules ...string) *Redactor {
+	return (&Redactor{}).With(rules...)
+}
+
+// DefaultRedactor returns a Redactor for DefaultRedactionRules.
+func DefaultRedactor() *Redactor {
+	return NewRedactor(DefaultRedactionRules...)
+}
+
+// With returns a Redactor with rules added to r's.
+func (r *Redactor) With(rules ...string) *Redactor {
+	n := &Redactor{}
+	if r != nil {
+		n.rules = append(n.rules, r.rules...)
+	}
+	for _, rule := range rules {
+		n.rules = append(n.rules, strings.Split(rule, "."))
+	}
+	return n
+}
+
+// RedactForm returns a copy of form with matching fields redacted.
+func (r *Redactor) RedactForm(form map[string]string) map[string]string {
+	out := make(map[string]string, len(form))
+	for key, value := range form {
+		if r.matches([]string{key}) {
+			value = "REDACTED"
+		}
+		out[key] = value
+	}
+	return out
+}
+
+// RedactJSON returns body with matching values redacted. Bodies that
+// aren't JSON are returned unchanged.
+func (r *Redactor) RedactJSON(body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return string(body)
+	}
+
+	out, err := json.Marshal(r.walk(nil, v))
+	if err != nil {
+		return string(body)
+	}
+	return string(out)
+}
+
+// redactRequest returns a copy of a request's form as it is logged.
+func (r *Redactor) redactRequest(form map[string]string) map[string]string {
+	if r == nil {
+		return r.RedactForm(form)
+	}
+	return r.With(requestRedactionRules[form["type"]]...).RedactForm(form)
+}
+
+// redactResponse returns a requestType response body as it is logged:
+// redacted, unless r is nil, and cut to maxLoggedResponse.
+func (r *Redactor) redactResponse(requestType string, body []byte) string {
+	out := string(body)
+	if r != nil {
+		out = r.With(responseRedactionRules[requestType]...).RedactJSON(body)
+	}
+	if len(out) > maxLoggedResponse {
+		out = fmt.Sprintf("%s... (%d bytes)", strings.ToValidUTF8(out[:maxLoggedResponse], ""), len(out))
+	}
+	return out
+}
+
+func (r *Redactor) walk(path []string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			childPath := append(path[:len(path):len(path)], key)
+			if r.matches(childPath) {
+				v[key] = "REDACTED"
+			} else {
+				v[key] = r.walk(childPath, child)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = r.walk(path, child)
+		}
+	}
+	return v
+}
+
+func (r *Redactor) matches(path []string) bool {
+	if r == nil {
+		return false
+	}
+	for _, rule := range r.rules {
+		if matchPath(rule, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPath(rule, path []string) bool {
+	if len(rule) == 0 {
+		return len(path) == 0
+	}
+	if rule[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchPath(rule[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 || (rule[0] != "*" && !strings.EqualFold(rule[0], path[0])) {
+		return false
+	}
+	return matchPath(rule[1:], path[1:])
+}
@@ -0,0 +1,106 @@
+package EpicAuth
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	body := `{"success":true,"sessionid":"s","info":{"username":"alice","ip":"1.2.3.4","hwid":"H",` +
+		`"subscriptions":[{"subscription":"default","key":"K1","expiry":1700000000},{"subscription":"premium","key":"K2"}],` +
+		`"meta":{"a":{"secret":"x"},"b":{"secret":"y"},"note":"kept"}}}`
+
+	tests := []struct {
+		name  string
+		rules []string
+		want  string
+	}{
+		{"default", DefaultRedactionRules,
+			`{"success":true,"sessionid":"REDACTED","info":{"username":"alice","ip":"REDACTED","hwid":"REDACTED",` +
+				`"subscriptions":[{"subscription":"default","key":"REDACTED","expiry":1700000000},{"subscription":"premium","key":"REDACTED"}],` +
+				`"meta":{"a":{"secret":"REDACTED"},"b":{"secret":"REDACTED"},"note":"kept"}}}`},
+		{"dotted path", []string{"info.username"},
+			`{"success":true,"sessionid":"s","info":{"username":"REDACTED","ip":"1.2.3.4","hwid":"H",` +
+				`"subscriptions":[{"subscription":"default","key":"K1","expiry":1700000000},{"subscription":"premium","key":"K2"}],` +
+				`"meta":{"a":{"secret":"x"},"b":{"secret":"y"},"note":"kept"}}}`},
+		{"array elements", []string{"info.subscriptions.subscription"},
+			`{"success":true,"sessionid":"s","info":{"username":"alice","ip":"1.2.3.4","hwid":"H",` +
+				`"subscriptions":[{"subscription":"REDACTED","key":"K1","expiry":1700000000},{"subscription":"REDACTED","key":"K2"}],` +
+				`"meta":{"a":{"secret":"x"},"b":{"secret":"y"},"note":"kept"}}}`},
+		{"wildcard", []string{"info.meta.*.secret", "INFO.HWID"},
+			`{"success":true,"sessionid":"s","info":{"username":"alice","ip":"1.2.3.4","hwid":"REDACTED",` +
+				`"subscriptions":[{"subscription":"default","key":"K1","expiry":1700000000},{"subscription":"premium","key":"K2"}],` +
+				`"meta":{"a":{"secret":"REDACTED"},"b":{"secret":"REDACTED"},"note":"kept"}}}`},
+		{"whole object", []string{"info"}, `{"success":true,"sessionid":"s","info":"REDACTED"}`},
+		{"none", nil, body},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got, want interface{}
+			if err := json.Unmarshal([]byte(NewRedactor(tt.rules...).RedactJSON([]byte(body))), &got); err != nil {
+				t.Fatal(err)
+			}
+			json.Unmarshal([]byte(tt.want), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("RedactJSON() = %v\nwant %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRedactorForm(t *testing.T) {
+	form := map[string]string{"type": "login", "username": "alice", "pass": "hunter2", "hwid": "H", "sessionid": "s"}
+
+	got := DefaultRedactor().RedactForm(form)
+	want := map[string]string{"type": "login", "username": "alice", "pass": "REDACTED", "hwid": "REDACTED", "sessionid": "REDACTED"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactForm() = %v, want %v", got, want)
+	}
+	if form["pass"] != "hunter2" {
+		t.Error("RedactForm() modified its argument")
+	}
+
+	got = DefaultRedactor().With("username").RedactForm(form)
+	if got["username"] != "REDACTED" || got["pass"] != "REDACTED" {
+		t.Errorf("RedactForm() with an extra rule = %v", got)
+	}
+
+	var none *Redactor
+	if got := none.RedactForm(form); !reflect.DeepEqual(got, form) {
+		t.Errorf("nil RedactForm() = %v, want %v", got, form)
+	}
+
+	setvar := map[string]string{"type": "setvar", "var": "notes", "data": "s3cret"}
+	if got := DefaultRedactor().redactRequest(setvar); got["data"] != "REDACTED" || got["var"] != "notes" {
+		t.Errorf("redactRequest(setvar) = %v, want data redacted", got)
+	}
+	if got := none.redactRequest(setvar); got["data"] != "s3cret" {
+		t.Errorf("nil redactRequest(setvar) = %v, want it logged as is", got)
+	}
+}
+
+func TestRedactResponse(t *testing.T) {
+	tests := []struct {
+		requestType string
+		body        string
+		want        string
+	}{
+		{"var", `{"success":true,"message":"v4lue"}`, `{"message":"REDACTED","success":true}`},
+		{"getvar", `{"success":true,"message":"ok","response":"v4lue"}`, `{"message":"ok","response":"REDACTED","success":true}`},
+		{"file", `{"success":true,"contents":"c0ffee"}`, `{"contents":"REDACTED","success":true}`},
+		{"log", `{"success":true,"message":"Logged"}`, `{"message":"Logged","success":true}`},
+	}
+	for _, tt := range tests {
+		if got := DefaultRedactor().redactResponse(tt.requestType, []byte(tt.body)); got != tt.want {
+			t.Errorf("redactResponse(%q) = %s, want %s", tt.requestType, got, tt.want)
+		}
+	}
+
+	long := `{"success":false,"message":"` + strings.Repeat("x", maxLoggedResponse) + `"}`
+	if got := DefaultRedactor().redactResponse("file", []byte(long)); len(got) > maxLoggedResponse+32 || !strings.HasSuffix(got, fmt.Sprintf("... (%d bytes)", len(long))) {
+		t.Errorf("redactResponse() of %d bytes = %d bytes ending %q", len(long), len(got), got[len(got)-20:])
+	}
+}
@@ -0,0 +1,173 @@
+package EpicAuth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Status is the part common to every API response.
+type Status struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+func (s Status) status() Status { return s }
+
+type statusReporter interface {
+	status() Status
+}
+
+type InitResponse struct {
+	Status
+	SessionID  string `json:"sessionid"`
+	NewSession bool   `json:"newSession"`
+	Download   string `json:"download"`
+}
+
+// LoginResponse is returned by the login, register and license calls.
+type LoginResponse struct {
+	Status
+	Info *UserInfo `json:"info"`
+}
+
+type UserInfo struct {
+	Username      string             `json:"username"`
+	IP            string             `json:"ip"`
+	HWID          FlexString         `json:"hwid"`
+	CreateDate    FlexString         `json:"createdate"`
+	LastLogin     FlexString         `json:"lastlogin"`
+	Subscriptions []SubscriptionInfo `json:"subscriptions"`
+}
+
+type SubscriptionInfo struct {
+	Subscription string     `json:"subscription"`
+	Key          string     `json:"key"`
+	Expiry       FlexString `json:"expiry"`
+	TimeLeft     FlexString `json:"timeleft"`
+}
+
+// ExpiresAt parses Expiry, a unix timestamp in seconds.
+func (s SubscriptionInfo) ExpiresAt() (time.Time, error) {
+	unix, err := strconv.ParseInt(string(s.Expiry), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: subscription expiry %q", ErrInvalidResponse, s.Expiry)
+	}
+	return time.Unix(unix, 0), nil
+}
+
+// StatsResponse is returned by the fetchStats call.
+type StatsResponse struct {
+	Status
+	AppInfo *AppInfo `json:"appinfo"`
+}
+
+type AppInfo struct {
+	NumUsers          FlexString `json:"numUsers"`
+	NumOnlineUsers    FlexString `json:"numOnlineUsers"`
+	NumKeys           FlexString `json:"numKeys"`
+	Version           FlexString `json:"version"`
+	CustomerPanelLink string     `json:"customerPanelLink"`
+}
+
+// Stats parses the application info. Missing numbers are zero.
+func (a AppInfo) Stats() (AppStats, error) {
+	stats := AppStats{Version: a.Version.String()}
+	for _, field := range []struct {
+		name  string
+		value FlexString
+		out   *int
+	}{
+		{"numUsers", a.NumUsers, &stats.NumUsers},
+		{"numOnlineUsers", a.NumOnlineUsers, &stats.NumOnlineUsers},
+		{"numKeys", a.NumKeys, &stats.NumKeys},
+	} {
+		if field.value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(string(field.value))
+		if err != nil {
+			return AppStats{}, fmt.Errorf("%w: %s %q", ErrInvalidResponse, field.name, field.value)
+		}
+		*field.out = n
+	}
+	if a.CustomerPanelLink != "" {
+		link, err := url.Parse(a.CustomerPanelLink)
+		if err != nil {
+			return AppStats{}, fmt.Errorf("%w: customerPanelLink: %v", ErrInvalidResponse, err)
+		}
+		stats.CustomerPanelURL = link
+	}
+	return stats, nil
+}
+
+// VarResponse is returned by the var and getvar calls. Application
+// variables are in Message, user variables in Response.
+type VarResponse struct {
+	Status
+	Response string `json:"response"`
+}
+
+type FileResponse struct {
+	Status
+	Contents string `json:"contents"`
+}
+
+type ChatMessagesResponse struct {
+	Status
+	Messages []ChatMessage `json:"messages"`
+}
+
+type ChatMessage struct {
+	Author    string     `json:"author"`
+	Message   string     `json:"message"`
+	Timestamp FlexString `json:"timestamp"`
+}
+
+// Time parses Timestamp, a unix timestamp in seconds.
+func (m ChatMessage) Time() (time.Time, error) {
+	unix, err := strconv.ParseInt(string(m.Timestamp), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: chat message timestamp %q", ErrInvalidResponse, m.Timestamp)
+	}
+	return time.Unix(unix, 0), nil
+}
+
+type OnlineUsersResponse struct {
+	Status
+	Users []OnlineUser `json:"users"`
+}
+
+type OnlineUser struct {
+	Credential string `json:"credential"`
+}
+
+// FlexString decodes a JSON string, number, boolean or null into a
+// string, since the server is not consistent about which it sends.
+type FlexString string
+
+func (s *FlexString) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*s = ""
+	case len(data) > 0 && data[0] == '"':
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		*s = FlexString(str)
+	case len(data) > 0 && (data[0] == '{' || data[0] == '['):
+		return fmt.Errorf("EpicAuth: cannot decode %s into a string", data)
+	default:
+		*s = FlexString(data)
+	}
+	return nil
+}
+
+func (s FlexString) String() string {
+	return string(s)
+}
@@ -0,0 +1,166 @@
+package EpicAuth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how requests that fail for transient reasons are
+// retried. Only idempotent request types are retried unless listed in
+// NonIdempotent.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry. It doubles on every
+	// further retry, up to MaxDelay if that is set.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Jitter randomizes each delay by up to this fraction, e.g. 0.2 for
+	// +/-20%.
+	Jitter float64
+	// RetryableStatus lists the HTTP status codes worth retrying.
+	RetryableStatus []int
+	// Retryable decides whether a transport error is worth retrying. Nil
+	// uses IsNetworkError.
+	Retryable func(error) bool
+	// NonIdempotent opts request types such as "register", "upgrade",
+	// "ban", "setvar" or "chatsend" in to retries.
+	NonIdempotent []string
+}
+
+// DefaultRetryPolicy is used by clients created without WithRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:     3,
+	BaseDelay:       500 * time.Millisecond,
+	MaxDelay:        5 * time.Second,
+	Jitter:          0.2,
+	RetryableStatus: []int{408, 429, 500, 502, 503, 504},
+}
+
+// idempotentTypes are the request types that are safe to send twice.
+var idempotentTypes = map[string]bool{
+	"init":           true,
+	"check":          true,
+	"var":            true,
+	"getvar":         true,
+	"file":           true,
+	"fetchStats":     true,
+	"fetchOnline":    true,
+	"chatget":        true,
+	"checkblacklist": true,
+}
+
+// StatusError is returned when the API answers with a retryable HTTP
+// status and no attempts are left.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("EpicAuth: server returned HTTP %d", e.StatusCode)
+}
+
+// IsNetworkError reports whether err looks like a transient network
+// failure: a timeout, a refused or reset connection, or a truncated
+// response.
+func IsNetworkError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
+
+func (p RetryPolicy) allows(requestType string) bool {
+	if p.MaxAttempts < 2 {
+		return false
+	}
+	if idempotentTypes[requestType] {
+		return true
+	}
+	for _, t := range p.NonIdempotent {
+		if t == requestType {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetryPolicy) retryableStatus(code int) bool {
+	for _, c := range p.RetryableStatus {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetryPolicy) retryableError(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsNetworkError(err)
+}
+
+// delay returns how long to wait before retry number n, starting at 1.
+func (p RetryPolicy) delay(n int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < n && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d += time.Duration(float64(d) * p.Jitter * (2*rand.Float64() - 1))
+	}
+	return d
+}
+
+// do calls send, retrying transient failures of requestType as p allows.
+func (p RetryPolicy) do(ctx context.Context, requestType string, send func(context.Context) (*RawResponse, error)) (*RawResponse, error) {
+	attempts := 1
+	if p.allows(requestType) {
+		attempts = p.MaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		response, err := send(ctx)
+		retry := false
+		if err != nil {
+			retry = p.retryableError(err)
+		} else if p.retryableStatus(response.StatusCode) {
+			err = &StatusError{StatusCode: response.StatusCode}
+			retry = true
+		}
+		if !retry || attempt >= attempts {
+			if err != nil {
+				return nil, err
+			}
+			return response, nil
+		}
+
+		timer := time.NewTimer(p.delay(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
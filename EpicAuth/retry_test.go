@@ -0,0 +1,135 @@
+package EpicAuth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyTransport fails the first failures sends with err, or with a bare
+// status code if err is nil, then delegates to next.
+type flakyTransport struct {
+	failures int
+	err      error
+	status   int
+	next     Transport
+	sends    int
+}
+
+func (t *flakyTransport) Send(ctx context.Context, form url.Values) (*RawResponse, error) {
+	t.sends++
+	if t.sends <= t.failures {
+		if t.err != nil {
+			return nil, t.err
+		}
+		return &RawResponse{StatusCode: t.status}, nil
+	}
+	return t.next.Send(ctx, form)
+}
+
+func TestRetry(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, RetryableStatus: []int{503}}
+
+	tests := []struct {
+		name        string
+		requestType string
+		policy      RetryPolicy
+		flaky       flakyTransport
+		wantSends   int
+		wantErr     bool
+	}{
+		{"idempotent recovers", "var", policy, flakyTransport{failures: 2, err: io.ErrUnexpectedEOF}, 3, false},
+		{"idempotent gives up", "var", policy, flakyTransport{failures: 5, err: syscall.ECONNRESET}, 3, true},
+		{"retryable status", "check", policy, flakyTransport{failures: 1, status: 503}, 2, false},
+		{"non-retryable error", "var", policy, flakyTransport{failures: 1, err: errors.New("boom")}, 1, true},
+		{"non-idempotent not retried", "setvar", policy, flakyTransport{failures: 1, err: io.EOF}, 1, true},
+		{"non-idempotent opted in", "setvar", RetryPolicy{MaxAttempts: 2, NonIdempotent: []string{"setvar"}}, flakyTransport{failures: 1, err: io.EOF}, 2, false},
+		{"disabled", "var", RetryPolicy{}, flakyTransport{failures: 1, err: io.EOF}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaky := tt.flaky
+			flaky.next = signedTransport(priv, time.Now(), 0, `{"success":true}`)
+			c := NewClient("test", "abcdefghij", "1.0",
+				WithPublicKey(hex.EncodeToString(pub)),
+				WithTransport(&flaky),
+				WithRetryPolicy(tt.policy),
+				WithLogger(nil),
+			)
+
+			_, err := c.doRequest(context.Background(), map[string]string{"type": tt.requestType})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("doRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if flaky.sends != tt.wantSends {
+				t.Errorf("sends = %d, want %d", flaky.sends, tt.wantSends)
+			}
+		})
+	}
+}
+
+func TestDefaultRetryPolicy(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	flaky := &flakyTransport{failures: 1, err: io.EOF, next: signedTransport(priv, time.Now(), 0, `{"success":true}`)}
+	c := NewClient("test", "abcdefghij", "1.0",
+		WithPublicKey(hex.EncodeToString(pub)),
+		WithTransport(flaky),
+		WithLogger(nil),
+	)
+
+	if _, err := c.doRequest(context.Background(), map[string]string{"type": "check"}); err != nil {
+		t.Errorf("doRequest() error = %v, want the default policy to retry", err)
+	}
+	if flaky.sends != 2 {
+		t.Errorf("sends = %d, want 2", flaky.sends)
+	}
+}
+
+func TestRetryStatusError(t *testing.T) {
+	flaky := &flakyTransport{failures: 10, status: 503}
+	c := NewClient("test", "abcdefghij", "1.0", WithTransport(flaky), WithLogger(nil),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, RetryableStatus: []int{503}}))
+
+	_, err := c.doRequest(context.Background(), map[string]string{"type": "check"})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != 503 {
+		t.Errorf("doRequest() error = %v, want HTTP 503", err)
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	flaky := &flakyTransport{failures: 10, err: io.EOF}
+	c := NewClient("test", "abcdefghij", "1.0", WithTransport(flaky), WithLogger(nil),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour}))
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := c.doRequest(ctx, map[string]string{"type": "check"})
+	if !errors.Is(err, context.Canceled) || flaky.sends != 1 {
+		t.Errorf("doRequest() = %v after %d sends", err, flaky.sends)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, w := range want {
+		if got := p.delay(i + 1); got != w {
+			t.Errorf("delay(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.delay(1); got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("delay(1) with jitter = %v", got)
+		}
+	}
+}
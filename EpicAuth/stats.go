@@ -0,0 +1,82 @@
+package EpicAuth
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// AppStats are the application's statistics.
+type AppStats struct {
+	NumUsers       int
+	NumOnlineUsers int
+	NumKeys        int
+	Version        string
+	// CustomerPanelURL is nil if the application has no customer panel.
+	CustomerPanelURL *url.URL
+}
+
+// StatsRefresher keeps a copy of the application's statistics up to date.
+// Create one with Client.StartStatsRefresher. It is safe for concurrent
+// use.
+type StatsRefresher struct {
+	*poller
+	c *Client
+
+	mu      sync.RWMutex
+	stats   AppStats
+	updated time.Time
+	err     error
+}
+
+// StartStatsRefresher fetches the statistics, then fetches them again
+// every interval until ctx is done, Stop is called or the session turns
+// out invalid. Failed refreshes keep the last statistics.
+func (c *Client) StartStatsRefresher(ctx context.Context, interval time.Duration) (*StatsRefresher, error) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	stats, err := c.FetchStatsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &StatsRefresher{
+		poller:  newPoller(ctx),
+		c:       c,
+		stats:   *stats,
+		updated: c.now(),
+	}
+	r.start(interval, r.refresh, nil)
+	return r, nil
+}
+
+// Stats returns the latest statistics and when they were fetched.
+func (r *StatsRefresher) Stats() (stats AppStats, updated time.Time) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.stats, r.updated
+}
+
+// Err returns the error of the last refresh, or nil if it succeeded. Once
+// Done is closed, it is the error that stopped the refresher, if any.
+func (r *StatsRefresher) Err() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.err
+}
+
+func (r *StatsRefresher) refresh(ctx context.Context) error {
+	stats, err := r.c.FetchStatsContext(ctx)
+	if ctx.Err() != nil {
+		return nil
+	}
+	r.mu.Lock()
+	r.err = err
+	if err == nil {
+		r.stats, r.updated = *stats, r.c.now()
+	}
+	r.mu.Unlock()
+	return err
+}
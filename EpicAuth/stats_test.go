@@ -0,0 +1,63 @@
+package EpicAuth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	EpicAuth "EpicAuth/EpicAuth"
+	"EpicAuth/EpicAuth/epicauthtest"
+)
+
+func TestAppInfoStats(t *testing.T) {
+	stats, err := EpicAuth.AppInfo{NumUsers: "3", NumOnlineUsers: "1", Version: "2.0"}.Stats()
+	if err != nil || stats.NumUsers != 3 || stats.NumOnlineUsers != 1 || stats.NumKeys != 0 || stats.Version != "2.0" || stats.CustomerPanelURL != nil {
+		t.Errorf("Stats() = %+v, %v", stats, err)
+	}
+	if _, err := (EpicAuth.AppInfo{NumKeys: "many"}).Stats(); !errors.Is(err, EpicAuth.ErrInvalidResponse) {
+		t.Errorf("Stats() with a non-numeric count error = %v, want %v", err, EpicAuth.ErrInvalidResponse)
+	}
+	if _, err := (EpicAuth.AppInfo{CustomerPanelLink: "://panel"}).Stats(); !errors.Is(err, EpicAuth.ErrInvalidResponse) {
+		t.Errorf("Stats() with a bad panel link error = %v, want %v", err, EpicAuth.ErrInvalidResponse)
+	}
+}
+
+func TestStatsRefresher(t *testing.T) {
+	s := newServer(t)
+	c := loggedIn(t, s)
+
+	r, err := c.StartStatsRefresher(context.Background(), 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("StartStatsRefresher() error = %v", err)
+	}
+	defer r.Stop()
+	if stats, updated := r.Stats(); stats.NumUsers != 1 || updated.IsZero() {
+		t.Errorf("Stats() = %+v, %v", stats, updated)
+	}
+
+	s.AddUser(epicauthtest.User{Username: "bob", Password: "pw"})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if stats, _ := r.Stats(); stats.NumUsers == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Stats() never showed the new user")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	s.ExpireSessions()
+	select {
+	case <-r.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("refresher still running after the session expired")
+	}
+	if err := r.Err(); !errors.Is(err, EpicAuth.ErrSessionExpired) {
+		t.Errorf("Err() = %v, want %v", err, EpicAuth.ErrSessionExpired)
+	}
+	if stats, _ := r.Stats(); stats.NumUsers != 2 {
+		t.Errorf("Stats() after a failed refresh = %+v, want the last statistics", stats)
+	}
+}
@@ -0,0 +1,213 @@
+package EpicAuth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTokenSize is the largest token accepted, far above any real one.
+const maxTokenSize = 4096
+
+// TokenSource supplies the token for the token system. Init reads it once
+// and sends the token and its hash from the same bytes.
+type TokenSource interface {
+	Token() ([]byte, error)
+}
+
+// TokenFunc adapts a function to TokenSource.
+type TokenFunc func() ([]byte, error)
+
+func (f TokenFunc) Token() ([]byte, error) {
+	return f()
+}
+
+// FileToken reads the token from a file, exactly as it is on disk.
+func FileToken(path string) TokenSource {
+	return TokenFunc(func() ([]byte, error) {
+		token, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %v", ErrTokenMissing, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("EpicAuth: reading token file: %w", err)
+		}
+		return token, nil
+	})
+}
+
+// EnvToken reads the token from an environment variable.
+func EnvToken(name string) TokenSource {
+	return TokenFunc(func() ([]byte, error) {
+		token, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: environment variable %s is not set", ErrTokenMissing, name)
+		}
+		return []byte(token), nil
+	})
+}
+
+// StaticToken returns a token kept in memory.
+func StaticToken(token string) TokenSource {
+	return TokenFunc(func() ([]byte, error) {
+		return []byte(token), nil
+	})
+}
+
+// Keyring is the part of an OS keyring, such as the Windows Credential
+// Manager or the macOS Keychain, that KeyringToken needs. Wrap the
+// keyring package of your choice to use it.
+type Keyring interface {
+	// Get returns the secret stored for service and user, or
+	// ErrTokenMissing if there is none.
+	Get(service, user string) (string, error)
+}
+
+// KeyringToken reads the token from a keyring.
+func KeyringToken(keyring Keyring, service, user string) TokenSource {
+	return TokenFunc(func() ([]byte, error) {
+		token, err := keyring.Get(service, user)
+		if err != nil {
+			return nil, fmt.Errorf("EpicAuth: reading token from keyring: %w", err)
+		}
+		return []byte(token), nil
+	})
+}
+
+// readToken reads the client's token and checks that it looks like one.
+func (c *Client) readToken() ([]byte, error) {
+	token, err := c.token.Token()
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(token)) == 0 {
+		return nil, fmt.Errorf("%w: token is empty", ErrTokenMissing)
+	}
+	if len(token) > maxTokenSize {
+		return nil, fmt.Errorf("%w: token is %d bytes long", ErrTokenInvalid, len(token))
+	}
+	if !utf8.Valid(token) {
+		return nil, fmt.Errorf("%w: token is not valid UTF-8", ErrTokenInvalid)
+	}
+	// A trailing newline, as editors like to add, is sent as is.
+	for _, r := range string(bytes.TrimRight(token, "\r\n")) {
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return nil, fmt.Errorf("%w: token contains %q", ErrTokenInvalid, r)
+		}
+	}
+	return token, nil
+}
+
+func tokenHash(token []byte) string {
+	hash := sha256.Sum256(token)
+	return hex.EncodeToString(hash[:])
+}
+
+// TokenEvent reports a re-initialization after the token changed. Err is
+// nil if it succeeded.
+type TokenEvent struct {
+	Time time.Time
+	Err  error
+}
+
+// TokenWatcher re-initializes a client when its token changes. Create one
+// with Client.WatchToken.
+type TokenWatcher struct {
+	*poller
+	c      *Client
+	events chan TokenEvent
+
+	// rejected is the hash of the last token the server turned down.
+	rejected string
+}
+
+// WatchToken reads the token every interval until ctx is done or Stop is
+// called. When it differs from the token the client was initialized with,
+// the client starts a new session, which also logs the user out. If the
+// server rejects the new token, the old session is kept and the token is
+// not tried again until it changes. Tokens that can't be read or fail
+// validation are ignored until they are fixed.
+func (c *Client) WatchToken(ctx context.Context, interval time.Duration) (*TokenWatcher, error) {
+	if c.token == nil {
+		return nil, fmt.Errorf("%w: no token source configured", ErrTokenMissing)
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	w := &TokenWatcher{
+		poller: newPoller(ctx),
+		c:      c,
+		events: make(chan TokenEvent, 16),
+	}
+	w.start(interval, w.poll, func() { close(w.events) })
+	return w, nil
+}
+
+// Events returns the re-initializations. Events are dropped rather than
+// block the watcher if nobody reads them. The channel is closed when the
+// watcher stops.
+func (w *TokenWatcher) Events() <-chan TokenEvent {
+	return w.events
+}
+
+// poll re-initializes the client if the token changed. Errors are only
+// reported as events: a rejected token doesn't end the watch.
+func (w *TokenWatcher) poll(ctx context.Context) error {
+	token, err := w.c.readToken()
+	if err != nil {
+		return nil
+	}
+	hash := tokenHash(token)
+	w.c.mu.RLock()
+	current := w.c.tokenHash
+	w.c.mu.RUnlock()
+	if hash == current || hash == w.rejected {
+		return nil
+	}
+
+	err = w.c.reinit(ctx, token)
+	if ctx.Err() != nil {
+		return nil
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		// The server won't change its mind about this token, so
+		// wait for another one rather than asking again every tick.
+		w.rejected = hash
+	}
+	select {
+	case w.events <- TokenEvent{Time: w.c.now(), Err: err}:
+	default:
+	}
+	return nil
+}
+
+// reinit starts a new session with token. The old session is only
+// dropped once the new one is up, so a token the server rejects leaves
+// the client as it was. A client running offline is back online once it
+// succeeds.
+func (c *Client) reinit(ctx context.Context, token []byte) error {
+	sessionID, err := c.initSession(ctx, token)
+	if err != nil {
+		return err
+	}
+	c.stopHeartbeat()
+	c.mu.Lock()
+	c.sessionID = sessionID
+	c.initialized = true
+	c.tokenHash = tokenHash(token)
+	c.user = UserInfo{}
+	c.offlineMode = false
+	c.offlineLogin = nil
+	c.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,138 @@
+package EpicAuth_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	EpicAuth "EpicAuth/EpicAuth"
+	"EpicAuth/EpicAuth/epicauthtest"
+)
+
+type fakeKeyring map[string]string
+
+func (k fakeKeyring) Get(service, user string) (string, error) {
+	secret, ok := k[service+"/"+user]
+	if !ok {
+		return "", EpicAuth.ErrTokenMissing
+	}
+	return secret, nil
+}
+
+func TestTokenSources(t *testing.T) {
+	s := epicauthtest.NewServer(epicauthtest.App{Token: "tok-123"})
+	defer s.Close()
+	dir := t.TempDir()
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	t.Setenv("EPICAUTH_TEST_TOKEN", "tok-123")
+
+	tests := []struct {
+		name   string
+		source EpicAuth.TokenSource
+		want   error
+	}{
+		{"file", EpicAuth.FileToken(write("ok", "tok-123")), nil},
+		{"env", EpicAuth.EnvToken("EPICAUTH_TEST_TOKEN"), nil},
+		{"static", EpicAuth.StaticToken("tok-123"), nil},
+		{"keyring", EpicAuth.KeyringToken(fakeKeyring{"app/alice": "tok-123"}, "app", "alice"), nil},
+		{"missing file", EpicAuth.FileToken(filepath.Join(dir, "nope")), EpicAuth.ErrTokenMissing},
+		{"empty file", EpicAuth.FileToken(write("empty", "\n")), EpicAuth.ErrTokenMissing},
+		{"unset env", EpicAuth.EnvToken("EPICAUTH_TEST_UNSET"), EpicAuth.ErrTokenMissing},
+		{"not in keyring", EpicAuth.KeyringToken(fakeKeyring{}, "app", "alice"), EpicAuth.ErrTokenMissing},
+		{"space", EpicAuth.StaticToken("tok 123"), EpicAuth.ErrTokenInvalid},
+		{"control character", EpicAuth.StaticToken("tok\x00123"), EpicAuth.ErrTokenInvalid},
+		{"rejected", EpicAuth.StaticToken("tok-456"), EpicAuth.ErrTokenRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClient(t, s, EpicAuth.WithTokenSource(tt.source))
+			err := c.Init()
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Init() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := newClient(t, s).WatchToken(context.Background(), time.Second); !errors.Is(err, EpicAuth.ErrTokenMissing) {
+		t.Errorf("WatchToken() without a token source error = %v, want %v", err, EpicAuth.ErrTokenMissing)
+	}
+}
+
+func TestWatchToken(t *testing.T) {
+	s := epicauthtest.NewServer(epicauthtest.App{Token: "tok-1"})
+	defer s.Close()
+	path := filepath.Join(t.TempDir(), "token.txt")
+	if err := os.WriteFile(path, []byte("tok-1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t, s, EpicAuth.WithTokenPath(path))
+	if err := c.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	session := c.SessionID()
+
+	w, err := c.WatchToken(context.Background(), 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WatchToken() error = %v", err)
+	}
+	defer w.Stop()
+
+	// A malformed token is ignored rather than ending the session.
+	os.WriteFile(path, []byte("tok 2"), 0600)
+	time.Sleep(50 * time.Millisecond)
+	if c.SessionID() != session {
+		t.Error("session changed for a malformed token")
+	}
+
+	s.SetToken("tok-2")
+	os.WriteFile(path, []byte("tok-2"), 0600)
+	select {
+	case event := <-w.Events():
+		if event.Err != nil {
+			t.Errorf("re-init error = %v", event.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("token change not noticed")
+	}
+	if !c.Initialized() || c.SessionID() == session {
+		t.Errorf("client initialized %v with session %q, want a new session", c.Initialized(), c.SessionID())
+	}
+
+	// A token the server rejects keeps the working session and is only
+	// tried once.
+	session = c.SessionID()
+	inits := func() (n int) {
+		for _, form := range s.Requests() {
+			if form.Get("type") == "init" {
+				n++
+			}
+		}
+		return n
+	}
+	before := inits()
+	os.WriteFile(path, []byte("tok-3"), 0600)
+	select {
+	case event := <-w.Events():
+		if !errors.Is(event.Err, EpicAuth.ErrTokenRejected) {
+			t.Errorf("re-init error = %v, want %v", event.Err, EpicAuth.ErrTokenRejected)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("token change not noticed")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := inits() - before; n != 1 {
+		t.Errorf("rejected token sent %d init requests, want 1", n)
+	}
+	if !c.Initialized() || c.SessionID() != session {
+		t.Errorf("client initialized %v with session %q after a rejected token, want %q", c.Initialized(), c.SessionID(), session)
+	}
+}
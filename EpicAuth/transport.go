@@ -0,0 +1,71 @@
+package EpicAuth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Transport sends a form encoded request to the EpicAuth API and returns
+// the raw, not yet verified, response.
+type Transport interface {
+	Send(ctx context.Context, form url.Values) (*RawResponse, error)
+}
+
+// TransportFunc adapts a function to the Transport interface.
+type TransportFunc func(ctx context.Context, form url.Values) (*RawResponse, error)
+
+func (f TransportFunc) Send(ctx context.Context, form url.Values) (*RawResponse, error) {
+	return f(ctx, form)
+}
+
+// RawResponse is a response as received from a Transport.
+type RawResponse struct {
+	StatusCode int
+	Body       []byte
+	Signature  string // x-signature-ed25519 header
+	Timestamp  string // x-signature-timestamp header
+}
+
+// HTTPTransport is the default Transport. It posts to URL using Client,
+// or a client with a 10 second timeout if Client is nil.
+type HTTPTransport struct {
+	URL    string
+	Client *http.Client
+}
+
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+func (t *HTTPTransport) Send(ctx context.Context, form url.Values) (*RawResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", t.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("EpicAuth: creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := t.Client
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("EpicAuth: sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("EpicAuth: reading response: %w", err)
+	}
+
+	return &RawResponse{
+		StatusCode: response.StatusCode,
+		Body:       responseBody,
+		Signature:  response.Header.Get("x-signature-ed25519"),
+		Timestamp:  response.Header.Get("x-signature-timestamp"),
+	}, nil
+}
@@ -0,0 +1,195 @@
+package EpicAuth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// UserVars is a typed view of the logged in user's variables. Values are
+// cached once read, and Set calls only change the cache until Flush
+// writes them to the server. It is safe for concurrent use.
+//
+// The cache belongs to one user: when another user logs in, cached values
+// and changes not yet flushed are dropped.
+type UserVars struct {
+	c *Client
+
+	mu       sync.Mutex
+	username string
+	values   map[string]string
+	missing  map[string]bool
+	dirty    map[string]string
+}
+
+// UserVars returns the client's user variable store.
+func (c *Client) UserVars() *UserVars {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.userVars == nil {
+		c.userVars = &UserVars{c: c}
+		c.userVars.reset(c.user.Username)
+	}
+	return c.userVars
+}
+
+// String returns a user variable. ok is false if the user doesn't have
+// the variable.
+func (v *UserVars) String(ctx context.Context, name string) (value string, ok bool, err error) {
+	v.mu.Lock()
+	v.checkUser()
+	value, ok = v.values[name]
+	missing := v.missing[name]
+	v.mu.Unlock()
+	if ok || missing {
+		return value, ok, nil
+	}
+
+	value, err = v.c.GetVarContext(ctx, name)
+	if err != nil && !errors.Is(err, ErrVarNotFound) {
+		return "", false, err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.checkUser()
+	// A value set while the request was in flight wins.
+	if local, ok := v.values[name]; ok {
+		return local, true, nil
+	}
+	if err != nil {
+		v.missing[name] = true
+		return "", false, nil
+	}
+	v.values[name] = value
+	return value, true, nil
+}
+
+// Int returns a user variable holding an integer.
+func (v *UserVars) Int(ctx context.Context, name string) (value int, ok bool, err error) {
+	s, ok, err := v.String(ctx, name)
+	if !ok || err != nil {
+		return 0, ok, err
+	}
+	value, err = strconv.Atoi(s)
+	if err != nil {
+		return 0, true, fmt.Errorf("EpicAuth: user variable %s: %w", name, err)
+	}
+	return value, true, nil
+}
+
+// Bool returns a user variable holding a boolean.
+func (v *UserVars) Bool(ctx context.Context, name string) (value bool, ok bool, err error) {
+	s, ok, err := v.String(ctx, name)
+	if !ok || err != nil {
+		return false, ok, err
+	}
+	value, err = strconv.ParseBool(s)
+	if err != nil {
+		return false, true, fmt.Errorf("EpicAuth: user variable %s: %w", name, err)
+	}
+	return value, true, nil
+}
+
+// JSON decodes a user variable holding JSON into out.
+func (v *UserVars) JSON(ctx context.Context, name string, out interface{}) (ok bool, err error) {
+	s, ok, err := v.String(ctx, name)
+	if !ok || err != nil {
+		return ok, err
+	}
+	if err := json.Unmarshal([]byte(s), out); err != nil {
+		return true, fmt.Errorf("EpicAuth: user variable %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// SetString sets a user variable. The server only sees it after Flush.
+func (v *UserVars) SetString(name, value string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.checkUser()
+	v.values[name] = value
+	v.dirty[name] = value
+	delete(v.missing, name)
+}
+
+// SetInt sets a user variable to an integer.
+func (v *UserVars) SetInt(name string, value int) {
+	v.SetString(name, strconv.Itoa(value))
+}
+
+// SetBool sets a user variable to a boolean.
+func (v *UserVars) SetBool(name string, value bool) {
+	v.SetString(name, strconv.FormatBool(value))
+}
+
+// SetJSON sets a user variable to value encoded as JSON.
+func (v *UserVars) SetJSON(name string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("EpicAuth: user variable %s: %w", name, err)
+	}
+	v.SetString(name, string(data))
+	return nil
+}
+
+// Pending returns the names of variables set since the last Flush.
+func (v *UserVars) Pending() []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.checkUser()
+	names := make([]string, 0, len(v.dirty))
+	for name := range v.dirty {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Flush writes the variables set since the last Flush to the server.
+// Variables that fail stay pending for the next Flush; the error joins
+// their errors.
+func (v *UserVars) Flush(ctx context.Context) error {
+	var errs []error
+	for _, name := range v.Pending() {
+		v.mu.Lock()
+		value, ok := v.dirty[name]
+		username := v.username
+		v.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		if err := v.c.SetVarContext(ctx, name, value); err != nil {
+			errs = append(errs, fmt.Errorf("EpicAuth: user variable %s: %w", name, err))
+			continue
+		}
+
+		v.mu.Lock()
+		// Keep the variable pending if it changed during the request.
+		if v.username == username && v.dirty[name] == value {
+			delete(v.dirty, name)
+		}
+		v.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// checkUser drops the cache if another user has logged in since it was
+// filled.
+func (v *UserVars) checkUser() {
+	if username := v.c.User().Username; username != v.username {
+		v.reset(username)
+	}
+}
+
+func (v *UserVars) reset(username string) {
+	v.username = username
+	v.values = make(map[string]string)
+	v.missing = make(map[string]bool)
+	v.dirty = make(map[string]string)
+}
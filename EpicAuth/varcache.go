@@ -0,0 +1,135 @@
+package EpicAuth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultVarCacheTTL is how long variables are cached unless
+// VarCacheConfig.TTL says otherwise.
+const DefaultVarCacheTTL = 5 * time.Minute
+
+// VarCacheConfig configures WithVarCache.
+type VarCacheConfig struct {
+	// TTL is how long a variable read with Var is cached. Defaults to
+	// DefaultVarCacheTTL.
+	TTL time.Duration
+	// TTLs overrides TTL for individual variables. A negative TTL never
+	// caches the variable.
+	TTLs map[string]time.Duration
+	// Concurrency is how many variables PrefetchVars loads at once.
+	// Defaults to 4.
+	Concurrency int
+}
+
+func (cfg VarCacheConfig) ttl(name string) time.Duration {
+	if ttl, ok := cfg.TTLs[name]; ok {
+		return ttl
+	}
+	return cfg.TTL
+}
+
+// varCache holds application variables read with Var. Failed reads
+// aren't cached.
+type varCache struct {
+	cfg VarCacheConfig
+
+	mu      sync.Mutex
+	entries map[string]varEntry
+}
+
+type varEntry struct {
+	value   string
+	expires time.Time
+}
+
+func (vc *varCache) get(name string, now time.Time) (string, bool) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	entry, ok := vc.entries[name]
+	if !ok || !now.Before(entry.expires) {
+		return "", false
+	}
+	return entry.value, true
+}
+
+func (vc *varCache) put(name, value string, now time.Time) {
+	ttl := vc.cfg.ttl(name)
+	if ttl <= 0 {
+		return
+	}
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	vc.entries[name] = varEntry{value: value, expires: now.Add(ttl)}
+}
+
+// PrefetchVars loads several application variables at once and returns
+// the ones it could read. With WithVarCache, it also fills the variable
+// cache, and variables still cached aren't requested again; without it,
+// every call goes to the server. The error joins the error of every
+// variable that failed.
+func (c *Client) PrefetchVars(names ...string) (map[string]string, error) {
+	return c.PrefetchVarsContext(context.Background(), names...)
+}
+
+// PrefetchVarsContext is like PrefetchVars but uses ctx for the requests.
+func (c *Client) PrefetchVarsContext(ctx context.Context, names ...string) (map[string]string, error) {
+	if err := c.CheckInit(); err != nil {
+		return nil, err
+	}
+
+	concurrency := 4
+	if c.vars != nil && c.vars.cfg.Concurrency > 0 {
+		concurrency = c.vars.cfg.Concurrency
+	}
+	sem := make(chan struct{}, concurrency)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var errs []error
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			value, err := c.VarContext(ctx, name)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			values[name] = value
+		}(name)
+	}
+	wg.Wait()
+	return values, errors.Join(errs...)
+}
+
+// InvalidateVar removes application variables from the cache, so the
+// next Var reads them from the server.
+func (c *Client) InvalidateVar(names ...string) {
+	if c.vars == nil {
+		return
+	}
+	c.vars.mu.Lock()
+	defer c.vars.mu.Unlock()
+	for _, name := range names {
+		delete(c.vars.entries, name)
+	}
+}
+
+// ClearVarCache removes every application variable from the cache.
+func (c *Client) ClearVarCache() {
+	if c.vars == nil {
+		return
+	}
+	c.vars.mu.Lock()
+	defer c.vars.mu.Unlock()
+	c.vars.entries = make(map[string]varEntry)
+}
@@ -0,0 +1,109 @@
+package EpicAuth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"EpicAuth/EpicAuth/epicauthtest"
+)
+
+func TestVarCache(t *testing.T) {
+	s := epicauthtest.NewServer(epicauthtest.App{})
+	defer s.Close()
+	for _, name := range []string{"a", "b", "c", "d", "e", "motd"} {
+		s.SetVar(name, "value of "+name)
+	}
+	varRequests := func() (n int) {
+		for _, form := range s.Requests() {
+			if form.Get("type") == "var" {
+				n++
+			}
+		}
+		return n
+	}
+
+	now := time.Now()
+	c := NewClient(epicauthtest.DefaultName, epicauthtest.DefaultOwnerID, epicauthtest.DefaultVersion,
+		WithAPIURL(s.URL),
+		WithPublicKey(s.PublicKey),
+		WithLogger(nil),
+		// The test moves the local clock, not the server's.
+		WithClockSkewTolerance(time.Hour),
+		WithVarCache(VarCacheConfig{
+			TTL:         time.Minute,
+			TTLs:        map[string]time.Duration{"motd": 10 * time.Minute, "e": -1},
+			Concurrency: 2,
+		}),
+	)
+	c.now = func() time.Time { return now }
+	if err := c.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	values, err := c.PrefetchVars("a", "b", "c", "d", "motd", "missing")
+	if len(values) != 5 || values["c"] != "value of c" {
+		t.Errorf("PrefetchVars() = %v", values)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Errorf("PrefetchVars() error = %v, want the missing variable's error", err)
+	}
+	if n := varRequests(); n != 6 {
+		t.Fatalf("PrefetchVars() sent %d requests, want 6", n)
+	}
+
+	for _, name := range []string{"a", "b", "motd"} {
+		if got, err := c.Var(name); err != nil || got != "value of "+name {
+			t.Errorf("Var(%q) = %q, %v", name, got, err)
+		}
+	}
+	c.Var("e")
+	c.Var("e")
+	if n := varRequests(); n != 8 {
+		t.Errorf("%d requests after reading cached variables, want 8", n)
+	}
+
+	now = now.Add(2 * time.Minute)
+	s.SetVar("a", "changed")
+	if got, _ := c.Var("a"); got != "changed" {
+		t.Errorf("Var() after the TTL = %q, want the new value", got)
+	}
+	if got, _ := c.Var("motd"); got != "value of motd" {
+		t.Errorf("Var() with a longer TTL = %q, want the cached value", got)
+	}
+
+	s.SetVar("motd", "changed")
+	c.InvalidateVar("motd")
+	if got, _ := c.Var("motd"); got != "changed" {
+		t.Errorf("Var() after InvalidateVar = %q, want the new value", got)
+	}
+
+	c.ClearVarCache()
+	before := varRequests()
+	c.Var("b")
+	if varRequests() != before+1 {
+		t.Error("Var() after ClearVarCache was served from the cache")
+	}
+}
+
+func TestVarCacheDefaultTTL(t *testing.T) {
+	s := epicauthtest.NewServer(epicauthtest.App{})
+	defer s.Close()
+	s.SetVar("motd", "hello")
+
+	c := NewClient(epicauthtest.DefaultName, epicauthtest.DefaultOwnerID, epicauthtest.DefaultVersion,
+		WithAPIURL(s.URL),
+		WithPublicKey(s.PublicKey),
+		WithLogger(nil),
+		WithVarCache(VarCacheConfig{}),
+	)
+	if err := c.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	c.Var("motd")
+	s.SetVar("motd", "changed")
+	if got, err := c.Var("motd"); err != nil || got != "hello" {
+		t.Errorf("Var() with a zero config = %q, %v, want the cached value", got, err)
+	}
+}
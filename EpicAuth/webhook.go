@@ -0,0 +1,107 @@
+package EpicAuth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// WebhookRequest is a request to a webhook set up on the dashboard. The
+// server sends it to the webhook's URL, so the URL and any secrets in it
+// never reach the application. Build one with NewWebhookRequest and send
+// it with Client.SendWebhook.
+type WebhookRequest struct {
+	WebID string
+	// Params are appended to the webhook's URL as "&key=value" pairs.
+	Params url.Values
+	// Body and ContentType are sent as the request body. No body sends
+	// a GET request.
+	Body        string
+	ContentType string
+
+	err error
+}
+
+// NewWebhookRequest returns a request to webhook webID.
+func NewWebhookRequest(webID string) *WebhookRequest {
+	return &WebhookRequest{WebID: webID, Params: url.Values{}}
+}
+
+// Param adds a query parameter.
+func (r *WebhookRequest) Param(key, value string) *WebhookRequest {
+	if r.Params == nil {
+		r.Params = url.Values{}
+	}
+	r.Params.Add(key, value)
+	return r
+}
+
+// JSON sets the body to v encoded as JSON. An error encoding v is
+// returned by SendWebhook.
+func (r *WebhookRequest) JSON(v interface{}) *WebhookRequest {
+	body, err := json.Marshal(v)
+	if err != nil {
+		r.err = fmt.Errorf("EpicAuth: encoding webhook body: %w", err)
+		return r
+	}
+	r.Body, r.ContentType = string(body), "application/json"
+	return r
+}
+
+// Form sets the body to form encoded values.
+func (r *WebhookRequest) Form(values url.Values) *WebhookRequest {
+	r.Body, r.ContentType = values.Encode(), "application/x-www-form-urlencoded"
+	return r
+}
+
+// WebhookResponse is the webhook's answer, relayed by the server.
+type WebhookResponse struct {
+	Status
+	Response json.RawMessage `json:"response"`
+}
+
+// Body returns the webhook's response body.
+func (r *WebhookResponse) Body() string {
+	var body string
+	if err := json.Unmarshal(r.Response, &body); err == nil {
+		return body
+	}
+	// Some servers relay JSON responses as is rather than as a string.
+	return string(r.Response)
+}
+
+// JSON decodes the webhook's response body into out.
+func (r *WebhookResponse) JSON(out interface{}) error {
+	if err := json.Unmarshal([]byte(r.Body()), out); err != nil {
+		return fmt.Errorf("EpicAuth: decoding webhook response: %w", err)
+	}
+	return nil
+}
+
+// SendWebhook sends r through the server and returns the webhook's
+// response.
+func (c *Client) SendWebhook(ctx context.Context, r *WebhookRequest) (*WebhookResponse, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if err := c.CheckInit(); err != nil {
+		return nil, err
+	}
+
+	var params string
+	if len(r.Params) > 0 {
+		params = "&" + r.Params.Encode()
+	}
+	var response WebhookResponse
+	if err := c.call(ctx, c.session(map[string]string{
+		"type":     "webhook",
+		"webid":    r.WebID,
+		"params":   params,
+		"body":     r.Body,
+		"conttype": r.ContentType,
+	}), &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
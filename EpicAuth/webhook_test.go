@@ -0,0 +1,63 @@
+package EpicAuth_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	EpicAuth "EpicAuth/EpicAuth"
+)
+
+func TestSendWebhook(t *testing.T) {
+	s := newServer(t)
+	s.SetWebhook("7kR0UedlVI", `{"ok":true,"count":2}`)
+	c := loggedIn(t, s)
+	ctx := context.Background()
+	lastForm := func() url.Values {
+		requests := s.Requests()
+		return requests[len(requests)-1]
+	}
+
+	response, err := c.SendWebhook(ctx, EpicAuth.NewWebhookRequest("7kR0UedlVI").
+		Param("ip", "1.1.1.1").
+		Param("note", "a&b").
+		JSON(map[string]string{"content": "hello"}))
+	if err != nil || !response.Success || response.Body() != `{"ok":true,"count":2}` {
+		t.Fatalf("SendWebhook() = %+v, %v", response, err)
+	}
+	var decoded struct {
+		OK    bool `json:"ok"`
+		Count int  `json:"count"`
+	}
+	if err := response.JSON(&decoded); err != nil || !decoded.OK || decoded.Count != 2 {
+		t.Errorf("JSON() = %+v, %v", decoded, err)
+	}
+	form := lastForm()
+	if form.Get("params") != "&ip=1.1.1.1&note=a%26b" || form.Get("body") != `{"content":"hello"}` || form.Get("conttype") != "application/json" {
+		t.Errorf("sent params %q, body %q, conttype %q", form.Get("params"), form.Get("body"), form.Get("conttype"))
+	}
+
+	if _, err := c.SendWebhook(ctx, EpicAuth.NewWebhookRequest("7kR0UedlVI").Form(url.Values{"type": {"init"}})); err != nil {
+		t.Fatalf("SendWebhook() with a form error = %v", err)
+	}
+	if form := lastForm(); form.Get("params") != "" || form.Get("body") != "type=init" || form.Get("conttype") != "application/x-www-form-urlencoded" {
+		t.Errorf("sent params %q, body %q, conttype %q", form.Get("params"), form.Get("body"), form.Get("conttype"))
+	}
+
+	sent := len(s.Requests())
+	if _, err := c.SendWebhook(ctx, EpicAuth.NewWebhookRequest("7kR0UedlVI").JSON(make(chan int))); err == nil || len(s.Requests()) != sent {
+		t.Errorf("SendWebhook() with an unencodable body error = %v, want it to fail before sending", err)
+	}
+
+	var apiErr *EpicAuth.APIError
+	if _, err := c.SendWebhook(ctx, EpicAuth.NewWebhookRequest("missing")); !errors.As(err, &apiErr) {
+		t.Errorf("SendWebhook() of a missing webhook error = %v, want an *APIError", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.SendWebhook(cancelled, EpicAuth.NewWebhookRequest("7kR0UedlVI")); !errors.Is(err, context.Canceled) {
+		t.Errorf("SendWebhook() with a cancelled context error = %v, want %v", err, context.Canceled)
+	}
+}
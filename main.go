@@ -2,6 +2,7 @@ package main
 
 import (
 	EpicAuthApp "EpicAuth/EpicAuth"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -15,13 +16,29 @@ func Input(message string) string {
 	return input
 }
 
+func Fail(err error) {
+	var apiErr *EpicAuthApp.APIError
+	if errors.As(err, &apiErr) && apiErr.Download != "" {
+		fmt.Println("New application version found! Downloading...")
+		EpicAuthApp.OpenURL(apiErr.Download)
+	} else {
+		fmt.Println(err)
+	}
+
+	time.Sleep(3 * time.Second)
+	os.Exit(1)
+}
+
 func main() {
-	EpicAuthApp.Api(
-		"EpicAuth",    // -- Application Name
+	err := EpicAuthApp.Api(
+		"EpicAuth",   // -- Application Name
 		"mpgOizljNW", // -- Owner ID
 		"1.1",        // -- Application Version
 		"",           // -- Token Path (PUT NULL OR LEAVE BLANK IF YOU DON'T WANT TO USE TOKEN SYSTEM)
 	)
+	if err != nil {
+		Fail(err)
+	}
 
 	fmt.Println("[1] Login")
 	fmt.Println("[2] Register")
@@ -34,28 +51,39 @@ func main() {
 		username := Input("Input username: ")
 		password := Input("Input password: ")
 
-		EpicAuthApp.Login(username, password)
+		_, err = EpicAuthApp.Login(username, password)
 	} else if ans == "2" {
 		username := Input("Input username: ")
 		password := Input("Input password: ")
 		license := Input("Input license: ")
 
-		EpicAuthApp.Register(username, password, license)
+		_, err = EpicAuthApp.Register(username, password, license)
 	} else if ans == "3" {
 		username := Input("Input username: ")
 		license := Input("Input license: ")
 
-		EpicAuthApp.Upgrade(username, license)
+		var message string
+		message, err = EpicAuthApp.Upgrade(username, license)
+		if err == nil {
+			fmt.Println(message)
+			fmt.Println("Please restart the application and login again to see the changes.")
+			time.Sleep(3 * time.Second)
+			os.Exit(1)
+		}
 	} else if ans == "4" {
 		license := Input("Input license: ")
 
-		EpicAuthApp.License(license)
+		_, err = EpicAuthApp.License(license)
 	} else {
 		fmt.Println("Invalid option")
 		time.Sleep(2 * time.Second)
 		main()
 	}
 
+	if err != nil {
+		Fail(err)
+	}
+
 	fmt.Println("\nUser Data:")
 	fmt.Println("   Username: ", EpicAuthApp.Username)
 	fmt.Println("   IP Address: ", EpicAuthApp.IP)